@@ -0,0 +1,97 @@
+package booking
+
+import (
+	"booking-app/internal/atomicfile"
+	"errors"
+	"os"
+	"sync"
+)
+
+// AuditLog keeps a permanent record of every cancellation of a conference.
+// Implementations must be safe for use by multiple goroutines.
+type AuditLog interface {
+	// Add appends a cancellation to the log
+	Add(cancellation Cancellation) error
+	// List returns all cancellations in the order they were added
+	List() ([]Cancellation, error)
+}
+
+// MemoryAuditLog keeps cancellations in memory only
+type MemoryAuditLog struct {
+	mu            sync.Mutex
+	cancellations []Cancellation
+}
+
+// NewMemoryAuditLog creates an empty in-memory audit log
+func NewMemoryAuditLog() *MemoryAuditLog {
+	return &MemoryAuditLog{cancellations: make([]Cancellation, 0)}
+}
+
+// Add appends a cancellation
+func (l *MemoryAuditLog) Add(cancellation Cancellation) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.cancellations = append(l.cancellations, cancellation)
+	return nil
+}
+
+// List returns a copy of all cancellations
+func (l *MemoryAuditLog) List() ([]Cancellation, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return copyCancellations(l.cancellations), nil
+}
+
+// FileAuditLog keeps cancellations in a JSON file so they survive restarts.
+// Like FileStore it rewrites the whole file through a temporary file.
+type FileAuditLog struct {
+	mu            sync.Mutex
+	path          string
+	cancellations []Cancellation
+}
+
+// NewFileAuditLog opens the JSON file at path, loading the cancellations it
+// already contains. The file is created on the first cancellation.
+// An empty or damaged file is an error rather than an empty start.
+func NewFileAuditLog(path string) (*FileAuditLog, error) {
+	l := &FileAuditLog{path: path, cancellations: make([]Cancellation, 0)}
+
+	err := atomicfile.ReadJSON(path, &l.cancellations)
+	if errors.Is(err, os.ErrNotExist) {
+		return l, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// Add appends a cancellation and writes the file
+func (l *FileAuditLog) Add(cancellation Cancellation) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	cancellations := append(copyCancellations(l.cancellations), cancellation)
+	if err := atomicfile.WriteJSON(l.path, cancellations); err != nil {
+		return err
+	}
+	l.cancellations = cancellations
+	return nil
+}
+
+// List returns a copy of all cancellations
+func (l *FileAuditLog) List() ([]Cancellation, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return copyCancellations(l.cancellations), nil
+}
+
+// copyCancellations returns a copy of cancellations that can be modified safely
+func copyCancellations(cancellations []Cancellation) []Cancellation {
+	cancellationsCopy := make([]Cancellation, len(cancellations))
+	copy(cancellationsCopy, cancellations)
+	return cancellationsCopy
+}
@@ -0,0 +1,413 @@
+// Package booking contains the core booking engine for conferences.
+// It keeps track of the available tickets and the bookings made so far
+// for each event in a Registry, so it can be reused by the CLI in main.go
+// or imported by other services.
+package booking
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// Errors returned by Book when a booking cannot be made
+var (
+	ErrNoTickets        = errors.New("booking: at least one ticket must be booked")
+	ErrNotEnoughTickets = errors.New("booking: not enough tickets remaining")
+	ErrBookingNotFound  = errors.New("booking: booking not found")
+	ErrSalesClosed      = errors.New("booking: ticket sales are closed")
+)
+
+// UserData groups all information about a single booking.
+// ID is unique within the conference and never reused, even after the
+// booking is cancelled; ConfirmationCode is the short code given to the
+// attendee to refer to the booking.
+// NumberOfTickets is the sum of the quantities of all line items;
+// Total is the Subtotal of the line items minus the Discount of the PromoCode.
+// BookedAt is zero for bookings stored before it was recorded.
+type UserData struct {
+	ID               uint       `json:"id"`
+	ConfirmationCode string     `json:"confirmationCode,omitempty"`
+	FirstName        string     `json:"firstName"`
+	LastName         string     `json:"lastName"`
+	Email            string     `json:"email"`
+	NumberOfTickets  uint       `json:"numberOfTickets"`
+	LineItems        []LineItem `json:"lineItems,omitempty"`
+	Subtotal         int64      `json:"subtotal"`
+	PromoCode        string     `json:"promoCode,omitempty"`
+	Discount         int64      `json:"discount,omitempty"`
+	Total            int64      `json:"total"`
+	Currency         string     `json:"currency,omitempty"`
+	BookedAt         time.Time  `json:"bookedAt"`
+}
+
+// Order is a request to book tickets, optionally with a promo code
+type Order struct {
+	FirstName string
+	LastName  string
+	Email     string
+	Items     []TicketOrder
+	PromoCode string
+}
+
+// Event describes a conference: its identifier, name, capacity and dates.
+// Events may sell several ticket tiers; Tickets is then the sum of their tickets.
+type Event struct {
+	ID       string    `json:"id"`
+	Name     string    `json:"name"`
+	Tickets  uint      `json:"tickets"`
+	Starts   time.Time `json:"starts"`
+	Ends     time.Time `json:"ends"`
+	Currency string    `json:"currency,omitempty"`
+	Tiers    []Tier    `json:"tiers,omitempty"`
+	// PromoCodes are the discount codes accepted for the event
+	PromoCodes []PromoCode `json:"promoCodes,omitempty"`
+	// Refunds is the refund policy for cancellations; nil refunds in full
+	Refunds *RefundPolicy `json:"refunds,omitempty"`
+}
+
+// Conference holds the ticket inventory and bookings for one event.
+// Every tier has its own inventory. Bookings are kept in a Store; the
+// remaining tickets are recovered from it when the conference is opened.
+// It is safe for use by multiple goroutines.
+type Conference struct {
+	// mu guards every field below, and serializes writes to the store
+	mu sync.Mutex
+
+	event       Event
+	remaining   map[string]uint
+	promoUsage  map[string]*promoUsage
+	store       Store
+	lastID      uint
+	codes       map[string]uint
+	salesClosed bool
+	limits      TicketLimits
+
+	waitlist        []WaitlistEntry
+	waitlistStore   WaitlistStore
+	lastWaitlistID  uint
+	offerWindow     time.Duration
+	onWaitlistOffer func(Event, WaitlistEntry)
+
+	audit              AuditLog
+	lastCancellationID uint
+
+	holds      []Hold
+	lastHoldID uint
+	// expiringHolds is true while the expiry goroutine runs; it is woken
+	// through holdsChanged whenever a hold is added
+	expiringHolds bool
+	holdsChanged  chan struct{}
+}
+
+// NewConference creates a conference for the event, keeping its bookings
+// in memory. All tickets are available when it is created.
+func NewConference(event Event) *Conference {
+	conference, _ := OpenConference(event, NewMemoryStore())
+	return conference
+}
+
+// OpenConference creates a conference for the event backed by the given store.
+// Bookings already in the store count against the tickets of their tiers,
+// so the remaining tickets and booking IDs continue where they left off.
+func OpenConference(event Event, store Store) (*Conference, error) {
+	bookings, err := store.List()
+	if err != nil {
+		return nil, err
+	}
+
+	tiers := event.TicketTiers()
+	event.Tickets = 0
+	remaining := make(map[string]uint)
+	for _, tier := range tiers {
+		event.Tickets += tier.Tickets
+		remaining[tier.ID] = tier.Tickets
+	}
+
+	conference := &Conference{
+		event:         event,
+		remaining:     remaining,
+		promoUsage:    make(map[string]*promoUsage),
+		codes:         make(map[string]uint),
+		store:         store,
+		waitlistStore: NewMemoryWaitlistStore(),
+		offerWindow:   DefaultOfferWindow,
+		audit:         NewMemoryAuditLog(),
+		holdsChanged:  make(chan struct{}, 1),
+	}
+	for _, booking := range bookings {
+		if booking.ID > conference.lastID {
+			conference.lastID = booking.ID
+		}
+		if booking.ConfirmationCode != "" {
+			conference.codes[booking.ConfirmationCode] = booking.ID
+		}
+		conference.countPromoCode(booking.PromoCode, booking.Email, 1)
+		for _, item := range bookedItems(booking, tiers[0].ID) {
+			if item.Quantity > remaining[item.Tier] {
+				remaining[item.Tier] = 0
+			} else {
+				remaining[item.Tier] -= item.Quantity
+			}
+		}
+	}
+
+	return conference, nil
+}
+
+// Copy returns a conference kept in memory with the same bookings, remaining
+// tickets, promo code use, ticket limits and waitlist as c, for example to
+// try out bookings without changing c. Tickets held in c stay off sale in
+// the copy, but the holds themselves are not copied.
+func (c *Conference) Copy() (*Conference, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	bookings, err := c.store.List()
+	if err != nil {
+		return nil, err
+	}
+
+	conference := &Conference{
+		event:              c.event,
+		remaining:          make(map[string]uint),
+		promoUsage:         make(map[string]*promoUsage),
+		store:              &MemoryStore{bookings: bookings},
+		lastID:             c.lastID,
+		codes:              make(map[string]uint),
+		salesClosed:        c.salesClosed,
+		limits:             c.limits,
+		waitlist:           copyWaitlist(c.waitlist),
+		waitlistStore:      NewMemoryWaitlistStore(),
+		lastWaitlistID:     c.lastWaitlistID,
+		offerWindow:        c.offerWindow,
+		audit:              NewMemoryAuditLog(),
+		lastCancellationID: c.lastCancellationID,
+		lastHoldID:         c.lastHoldID,
+		holdsChanged:       make(chan struct{}, 1),
+	}
+	for tierID, remaining := range c.remaining {
+		conference.remaining[tierID] = remaining
+	}
+	for code, usage := range c.promoUsage {
+		usageCopy := &promoUsage{total: usage.total, byEmail: make(map[string]int)}
+		for email, uses := range usage.byEmail {
+			usageCopy.byEmail[email] = uses
+		}
+		conference.promoUsage[code] = usageCopy
+	}
+	for code, id := range c.codes {
+		conference.codes[code] = id
+	}
+	return conference, nil
+}
+
+// bookedItems returns the line items of a booking. Bookings made before
+// tiers existed only have a ticket count, which belongs to defaultTier.
+func bookedItems(booking UserData, defaultTier string) []LineItem {
+	if len(booking.LineItems) > 0 {
+		return booking.LineItems
+	}
+	return []LineItem{{Tier: defaultTier, Quantity: booking.NumberOfTickets}}
+}
+
+// Event returns the description of the conference
+func (c *Conference) Event() Event {
+	return c.event
+}
+
+// ID returns the identifier of the conference
+func (c *Conference) ID() string {
+	return c.event.ID
+}
+
+// Name returns the name of the conference
+func (c *Conference) Name() string {
+	return c.event.Name
+}
+
+// Tickets returns the total number of tickets of the conference over all tiers
+func (c *Conference) Tickets() uint {
+	return c.event.Tickets
+}
+
+// Tiers returns the ticket tiers sold for the conference
+func (c *Conference) Tiers() []Tier {
+	return c.event.TicketTiers()
+}
+
+// Tier returns the tier with the given ID
+func (c *Conference) Tier(id string) (Tier, error) {
+	for _, tier := range c.event.TicketTiers() {
+		if tier.ID == id {
+			return tier, nil
+		}
+	}
+	return Tier{}, ErrUnknownTier
+}
+
+// Remaining returns the number of tickets that can still be booked over all tiers
+func (c *Conference) Remaining() uint {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var total uint
+	for tierID := range c.remaining {
+		total += c.forSale(tierID)
+	}
+	return total
+}
+
+// RemainingInTier returns the number of tickets of one tier that can still be booked
+func (c *Conference) RemainingInTier(tierID string) uint {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.forSale(tierID)
+}
+
+// forSale returns the tickets of a tier that newcomers can book or hold.
+// While anyone waits for the tier without an offer, freed tickets are kept
+// for the waitlist, so nobody gets ahead of the people already waiting.
+// Callers must hold c.mu.
+func (c *Conference) forSale(tierID string) uint {
+	if c.waitingFor(tierID) {
+		return 0
+	}
+	return c.remaining[tierID]
+}
+
+// Book books tickets of the first tier, which is the only tier of events
+// without tiers. See BookOrder for booking several tiers at once.
+func (c *Conference) Book(userTickets uint, firstName string, lastName string, email string) (UserData, error) {
+	return c.BookOrder(Order{
+		FirstName: firstName,
+		LastName:  lastName,
+		Email:     email,
+		Items:     []TicketOrder{{Tier: c.Tiers()[0].ID, Quantity: userTickets}},
+	})
+}
+
+// BookOrder updates the remaining tickets of every ordered tier and saves the
+// booking, priced per tier and discounted by the promo code, in the store.
+// Names are stored in their normalized form (see NormalizeName). Checking
+// availability, ticket limits and promo code limits and reserving the
+// tickets happen under a single lock, so concurrent callers can never book
+// more tickets than are available or allowed per person, or redeem a code
+// more often than allowed.
+func (c *Conference) BookOrder(order Order) (UserData, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.bookLocked(order, false)
+}
+
+// bookLocked implements BookOrder. Tickets that are held for the order are
+// handed back to remaining before, so they count as available even while
+// others are waiting for the tier; otherwise only tickets for sale count.
+// Callers must hold c.mu.
+func (c *Conference) bookLocked(order Order, held bool) (UserData, error) {
+	if c.salesClosed {
+		return UserData{}, ErrSalesClosed
+	}
+
+	code, err := c.newConfirmationCode()
+	if err != nil {
+		return UserData{}, err
+	}
+	var userData = UserData{
+		ID:               c.lastID + 1,
+		ConfirmationCode: code,
+		FirstName:        NormalizeName(order.FirstName),
+		LastName:         NormalizeName(order.LastName),
+		Email:            order.Email,
+		Currency:         c.event.Currency,
+		BookedAt:         time.Now(),
+	}
+
+	// The same tier may appear more than once; check the combined quantity
+	requested := make(map[string]uint)
+	for _, ticketOrder := range order.Items {
+		tier, err := c.Tier(ticketOrder.Tier)
+		if err != nil {
+			return UserData{}, err
+		}
+		if ticketOrder.Quantity == 0 {
+			continue
+		}
+		requested[tier.ID] += ticketOrder.Quantity
+		available := c.remaining[tier.ID]
+		if !held {
+			available = c.forSale(tier.ID)
+		}
+		if requested[tier.ID] > available {
+			return UserData{}, ErrNotEnoughTickets
+		}
+
+		item := LineItem{Tier: tier.ID, Quantity: ticketOrder.Quantity, UnitPrice: tier.Price, Total: tier.Price * int64(ticketOrder.Quantity)}
+		userData.LineItems = append(userData.LineItems, item)
+		userData.NumberOfTickets += item.Quantity
+		userData.Subtotal += item.Total
+	}
+	if userData.NumberOfTickets == 0 {
+		return UserData{}, ErrNoTickets
+	}
+	if err := c.checkTicketLimits(userData, false); err != nil {
+		return UserData{}, err
+	}
+
+	userData.Total = userData.Subtotal
+	if order.PromoCode != "" {
+		promo, discount, err := c.applyPromoCode(order.PromoCode, userData.Email, userData.LineItems, time.Now())
+		if err != nil {
+			return UserData{}, err
+		}
+		userData.PromoCode = promo.Code
+		userData.Discount = discount
+		userData.Total = userData.Subtotal - discount
+	}
+
+	// Only reserve the tickets once the booking is safely stored
+	if err := c.store.Save(userData); err != nil {
+		return UserData{}, err
+	}
+	c.lastID = userData.ID
+	c.codes[userData.ConfirmationCode] = userData.ID
+	for tierID, quantity := range requested {
+		c.remaining[tierID] -= quantity
+	}
+	c.countPromoCode(userData.PromoCode, userData.Email, 1)
+
+	return userData, nil
+}
+
+// CloseSales makes every further call to Book fail with ErrSalesClosed,
+// for example while the application is shutting down
+func (c *Conference) CloseSales() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.salesClosed = true
+}
+
+// Get returns the booking with the given ID
+func (c *Conference) Get(id uint) (UserData, error) {
+	return c.store.Get(id)
+}
+
+// Bookings returns all bookings made so far
+func (c *Conference) Bookings() ([]UserData, error) {
+	return c.store.List()
+}
+
+// FirstNames extracts a list of first names from the bookings
+func (c *Conference) FirstNames() ([]string, error) {
+	bookings, err := c.store.List()
+	if err != nil {
+		return nil, err
+	}
+
+	firstNames := []string{}
+	for _, booking := range bookings {
+		firstNames = append(firstNames, booking.FirstName)
+	}
+	return firstNames, nil
+}
@@ -0,0 +1,124 @@
+package booking
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestConcurrentBookingsNeverOversell races thousands of bookings, holds and
+// cancellations against one conference. Run it with -race.
+func TestConcurrentBookingsNeverOversell(t *testing.T) {
+	const capacity = 500
+	const workers = 4000
+	conference := NewConference(Event{ID: "stress", Name: "Stress Test", Tickets: capacity})
+
+	var wg sync.WaitGroup
+	errs := make(chan error, workers)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			tickets := uint(i%3 + 1)
+			email := fmt.Sprintf("user%v@example.com", i)
+			order := Order{FirstName: "Stress", LastName: "Tester", Email: email}
+
+			var err error
+			switch i % 4 {
+			case 0:
+				_, err = conference.Book(tickets, "Stress", "Tester", email)
+			case 1:
+				var hold Hold
+				hold, err = conference.Reserve([]TicketOrder{{Tier: DefaultTierID, Quantity: tickets}}, time.Minute)
+				if err == nil {
+					_, err = conference.Confirm(hold.ID, order)
+				}
+			case 2:
+				var hold Hold
+				hold, err = conference.Reserve([]TicketOrder{{Tier: DefaultTierID, Quantity: tickets}}, time.Minute)
+				if err == nil {
+					err = conference.Release(hold.ID)
+				}
+			case 3:
+				var booked UserData
+				booked, err = conference.Book(tickets, "Stress", "Tester", email)
+				if err == nil && tickets > 1 {
+					_, err = conference.CancelTickets(booked.ID, []TicketOrder{{Tier: DefaultTierID, Quantity: 1}}, "partial")
+				} else if err == nil {
+					_, err = conference.Cancel(booked.ID)
+				}
+			}
+			if err != nil && !errors.Is(err, ErrNotEnoughTickets) {
+				errs <- err
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	bookings, err := conference.Bookings()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var booked uint
+	ids := make(map[uint]bool)
+	codes := make(map[string]bool)
+	for _, booking := range bookings {
+		booked += booking.NumberOfTickets
+		if ids[booking.ID] {
+			t.Errorf("booking ID %v appears twice", booking.ID)
+		}
+		ids[booking.ID] = true
+		if codes[booking.ConfirmationCode] {
+			t.Errorf("confirmation code %v appears twice", booking.ConfirmationCode)
+		}
+		codes[booking.ConfirmationCode] = true
+	}
+
+	if held := len(conference.Holds()); held != 0 {
+		t.Errorf("%v holds left over", held)
+	}
+	if remaining := conference.Remaining(); booked+remaining != capacity {
+		t.Errorf("booked %v + remaining %v tickets, want capacity %v", booked, remaining, capacity)
+	}
+	if booked == 0 {
+		t.Error("no booking succeeded")
+	}
+}
+
+func TestCopyLeavesTheOriginalAlone(t *testing.T) {
+	conference := NewConference(Event{ID: "copy", Name: "Copy", Currency: "EUR",
+		Tiers:      []Tier{{ID: DefaultTierID, Name: "General", Price: 1000, Tickets: 10}},
+		PromoCodes: []PromoCode{{Code: "ONCE", Percent: 10, MaxUses: 1}}})
+	if _, err := conference.Book(2, "Ada", "Lovelace", "ada@example.com"); err != nil {
+		t.Fatal(err)
+	}
+
+	trial, err := conference.Copy()
+	if err != nil {
+		t.Fatal(err)
+	}
+	order := Order{FirstName: "Grace", LastName: "Hopper", Email: "grace@example.com",
+		Items: []TicketOrder{{Tier: DefaultTierID, Quantity: 3}}, PromoCode: "ONCE"}
+	if _, err := trial.BookOrder(order); err != nil {
+		t.Fatalf("booking the copy: %v", err)
+	}
+	if _, err := trial.BookOrder(order); !errors.Is(err, ErrPromoUsedUp) {
+		t.Errorf("second use of the code in the copy = %v, want ErrPromoUsedUp", err)
+	}
+
+	if remaining := conference.Remaining(); remaining != 8 {
+		t.Errorf("original Remaining = %v, want 8", remaining)
+	}
+	if remaining := trial.Remaining(); remaining != 5 {
+		t.Errorf("copy Remaining = %v, want 5", remaining)
+	}
+	if err := conference.CheckPromoCode("ONCE", "grace@example.com", order.Items); err != nil {
+		t.Errorf("the code is used up in the original: %v", err)
+	}
+}
@@ -0,0 +1,234 @@
+package booking
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Errors returned when cancelling tickets
+var (
+	// ErrCancelTooMany is returned when more tickets are cancelled than the booking holds
+	ErrCancelTooMany = errors.New("booking: cannot cancel more tickets than were booked")
+	// ErrTierNotBooked is returned when cancelling tickets of a tier the booking has none of
+	ErrTierNotBooked = errors.New("booking: the booking has no tickets of that tier")
+)
+
+// RefundPolicy decides how much of the price is refunded on cancellation.
+// Events without a policy refund cancelled tickets in full.
+type RefundPolicy struct {
+	// Deadline is the last moment cancellations are refunded; cancellations
+	// after it are still accepted but refund nothing. Zero means no deadline.
+	Deadline time.Time `json:"deadline"`
+	// FeePercent of the refundable amount (0-100) is kept as a cancellation fee
+	FeePercent int64 `json:"feePercent,omitempty"`
+}
+
+// refund splits the amount paid for cancelled tickets into fee and refund
+func (p *RefundPolicy) refund(amount int64, now time.Time) (fee int64, refund int64) {
+	if p == nil {
+		return 0, amount
+	}
+	if !p.Deadline.IsZero() && now.After(p.Deadline) {
+		return amount, 0
+	}
+	fee = amount * p.FeePercent / 100
+	return fee, amount - fee
+}
+
+// Cancellation is the audit record of cancelling all or some tickets of a booking.
+// Amount is the share of the booking total paid for the cancelled tickets,
+// of which Fee is kept and Refund is paid back.
+type Cancellation struct {
+	ID              uint       `json:"id"`
+	BookingID       uint       `json:"bookingId"`
+	FirstName       string     `json:"firstName"`
+	LastName        string     `json:"lastName"`
+	Email           string     `json:"email"`
+	Items           []LineItem `json:"items"`
+	NumberOfTickets uint       `json:"numberOfTickets"`
+	// Full is true when the whole booking was cancelled and no longer exists
+	Full        bool      `json:"full"`
+	Amount      int64     `json:"amount"`
+	Fee         int64     `json:"fee"`
+	Refund      int64     `json:"refund"`
+	Currency    string    `json:"currency,omitempty"`
+	Reason      string    `json:"reason,omitempty"`
+	CancelledAt time.Time `json:"cancelledAt"`
+}
+
+// SetAuditLog replaces the in-memory audit log of the conference, for
+// example with a FileAuditLog. Cancellation IDs continue after the ones
+// already in the log, and booking IDs after every cancelled booking, so the
+// ID of a booking that was cancelled is never given to a new one.
+func (c *Conference) SetAuditLog(audit AuditLog) error {
+	cancellations, err := audit.List()
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.audit = audit
+	c.lastCancellationID = 0
+	for _, cancellation := range cancellations {
+		if cancellation.ID > c.lastCancellationID {
+			c.lastCancellationID = cancellation.ID
+		}
+		if cancellation.BookingID > c.lastID {
+			c.lastID = cancellation.BookingID
+		}
+	}
+	return nil
+}
+
+// Cancellations returns the audit records of all cancellations so far
+func (c *Conference) Cancellations() ([]Cancellation, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.audit.List()
+}
+
+// Cancel cancels the whole booking with the given ID (see CancelTickets)
+func (c *Conference) Cancel(id uint) (Cancellation, error) {
+	return c.CancelTickets(id, nil, "")
+}
+
+// CancelTickets cancels some tickets of the booking with the given ID, or all
+// of them if items is empty. The tickets go back to the remaining tickets of
+// their tiers, offered to the waitlist first, and the refund is worked out
+// with the refund policy of the event. A booking left without tickets is
+// deleted and its promo code use is released.
+// Every cancellation is added to the audit log before the booking changes, so
+// the ID of a deleted booking is always on record and never reused after a
+// restart. If the audit log cannot be written nothing is cancelled; if only
+// the store fails afterwards, the record describes a cancellation that did
+// not happen, which is the safer mistake.
+func (c *Conference) CancelTickets(id uint, items []TicketOrder, reason string) (Cancellation, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	booking, err := c.store.Get(id)
+	if err != nil {
+		return Cancellation{}, err
+	}
+	for _, item := range items {
+		if _, err := c.Tier(item.Tier); err != nil {
+			return Cancellation{}, err
+		}
+	}
+
+	kept, cancelled, err := splitLineItems(bookedItems(booking, c.event.TicketTiers()[0].ID), items)
+	if err != nil {
+		return Cancellation{}, err
+	}
+
+	now := time.Now()
+	cancellation := Cancellation{
+		ID:          c.lastCancellationID + 1,
+		BookingID:   booking.ID,
+		FirstName:   booking.FirstName,
+		LastName:    booking.LastName,
+		Email:       booking.Email,
+		Items:       cancelled,
+		Full:        len(kept) == 0,
+		Currency:    booking.Currency,
+		Reason:      reason,
+		CancelledAt: now,
+	}
+	var value int64
+	for _, item := range cancelled {
+		cancellation.NumberOfTickets += item.Quantity
+		value += item.Total
+	}
+
+	// A promo discount is shared by the tickets in proportion to their price
+	switch {
+	case cancellation.Full:
+		cancellation.Amount = booking.Total
+	case booking.Subtotal > 0:
+		cancellation.Amount = value * booking.Total / booking.Subtotal
+	}
+	cancellation.Fee, cancellation.Refund = c.event.Refunds.refund(cancellation.Amount, now)
+
+	if err := c.audit.Add(cancellation); err != nil {
+		return Cancellation{}, fmt.Errorf("booking: recording cancellation of booking %v: %w", id, err)
+	}
+	c.lastCancellationID = cancellation.ID
+
+	if cancellation.Full {
+		err = c.store.Delete(id)
+	} else {
+		booking.LineItems = kept
+		booking.NumberOfTickets -= cancellation.NumberOfTickets
+		booking.Subtotal -= value
+		booking.Total -= cancellation.Amount
+		booking.Discount = booking.Subtotal - booking.Total
+		err = c.store.Save(booking)
+	}
+	if err != nil {
+		return Cancellation{}, err
+	}
+
+	for _, item := range cancelled {
+		c.remaining[item.Tier] += item.Quantity
+	}
+	if cancellation.Full {
+		c.countPromoCode(booking.PromoCode, booking.Email, -1)
+		delete(c.codes, booking.ConfirmationCode)
+	}
+	c.offerFreedTickets()
+	return cancellation, nil
+}
+
+// splitLineItems takes the ordered quantities out of the booked line items.
+// It returns the line items that are kept and the ones that are cancelled;
+// with no orders everything is cancelled. Ordering a tier that was not booked
+// fails with ErrTierNotBooked, more tickets than were booked with ErrCancelTooMany.
+func splitLineItems(booked []LineItem, orders []TicketOrder) (kept []LineItem, cancelled []LineItem, err error) {
+	if len(orders) == 0 {
+		return nil, booked, nil
+	}
+
+	toCancel := make(map[string]uint)
+	var total uint
+	for _, order := range orders {
+		toCancel[order.Tier] += order.Quantity
+		total += order.Quantity
+	}
+	if total == 0 {
+		return nil, nil, ErrNoTickets
+	}
+
+	bookedTiers := make(map[string]bool)
+	for _, item := range booked {
+		bookedTiers[item.Tier] = true
+		quantity := toCancel[item.Tier]
+		if quantity > item.Quantity {
+			quantity = item.Quantity
+		}
+		toCancel[item.Tier] -= quantity
+
+		if quantity > 0 {
+			cancelled = append(cancelled, LineItem{Tier: item.Tier, Quantity: quantity, UnitPrice: item.UnitPrice, Total: item.UnitPrice * int64(quantity)})
+		}
+		if quantity < item.Quantity {
+			item.Quantity -= quantity
+			item.Total = item.UnitPrice * int64(item.Quantity)
+			kept = append(kept, item)
+		}
+	}
+	for tier, quantity := range toCancel {
+		if quantity > 0 && !bookedTiers[tier] {
+			return nil, nil, ErrTierNotBooked
+		}
+	}
+	for _, quantity := range toCancel {
+		if quantity > 0 {
+			return nil, nil, ErrCancelTooMany
+		}
+	}
+	return kept, cancelled, nil
+}
@@ -0,0 +1,63 @@
+package booking
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCancelTicketsChecksTiers(t *testing.T) {
+	conference := NewConference(Event{ID: "tiers", Name: "Tiers", Tiers: []Tier{
+		{ID: "general", Name: "General", Tickets: 10},
+		{ID: "student", Name: "Student", Tickets: 10},
+	}})
+	booked, err := conference.BookOrder(Order{FirstName: "Ada", LastName: "Lovelace", Email: "ada@example.com",
+		Items: []TicketOrder{{Tier: "general", Quantity: 2}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		items []TicketOrder
+		want  error
+	}{
+		{[]TicketOrder{{Tier: "student", Quantity: 1}}, ErrTierNotBooked},
+		{[]TicketOrder{{Tier: "vip", Quantity: 1}}, ErrUnknownTier},
+		{[]TicketOrder{{Tier: "general", Quantity: 3}}, ErrCancelTooMany},
+	}
+	for _, test := range tests {
+		if _, err := conference.CancelTickets(booked.ID, test.items, ""); !errors.Is(err, test.want) {
+			t.Errorf("CancelTickets(%+v) = %v, want %v", test.items, err, test.want)
+		}
+	}
+	if remaining := conference.RemainingInTier("general"); remaining != 8 {
+		t.Errorf("RemainingInTier(general) = %v after failed cancellations, want 8", remaining)
+	}
+}
+
+// failingAuditLog refuses every cancellation
+type failingAuditLog struct{ MemoryAuditLog }
+
+func (l *failingAuditLog) Add(Cancellation) error {
+	return errors.New("disk full")
+}
+
+func TestCancelKeepsBookingWhenAuditFails(t *testing.T) {
+	conference := NewConference(Event{ID: "audit", Name: "Audit", Tickets: 10})
+	booked, err := conference.Book(2, "Ada", "Lovelace", "ada@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := conference.SetAuditLog(&failingAuditLog{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := conference.Cancel(booked.ID); err == nil {
+		t.Fatal("Cancel succeeded without an audit record")
+	}
+	if _, err := conference.Get(booked.ID); err != nil {
+		t.Errorf("booking %v is gone after the audit failed: %v", booked.ID, err)
+	}
+	if remaining := conference.Remaining(); remaining != 8 {
+		t.Errorf("Remaining = %v, want 8", remaining)
+	}
+}
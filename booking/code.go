@@ -0,0 +1,82 @@
+package booking
+
+import (
+	"crypto/rand"
+	"errors"
+	"math/big"
+	"strings"
+)
+
+// codeAlphabet leaves out characters that are easily mistaken for each
+// other when read aloud or written down: 0/O, 1/I/L, 5/S, 2/Z, 8/B and U/V
+const codeAlphabet = "34679ACDEFGHJKMNPQRTWXY"
+
+// codeLength is the number of characters of a confirmation code
+const codeLength = 8
+
+// maxCodeAttempts bounds the retries when a new code collides with an existing one
+const maxCodeAttempts = 10
+
+// ErrNoConfirmationCode is returned when no unused confirmation code could be generated
+var ErrNoConfirmationCode = errors.New("booking: could not generate a unique confirmation code")
+
+// NormalizeConfirmationCode upper-cases a code as typed by a person and
+// removes the separators and spaces they may have added, e.g. "abcd-efgh"
+func NormalizeConfirmationCode(code string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '-' || r == ' ' {
+			return -1
+		}
+		return r
+	}, strings.ToUpper(code))
+}
+
+// FormatConfirmationCode splits a code into two groups for display, e.g. "ACDE-FGHJ"
+func FormatConfirmationCode(code string) string {
+	if len(code) != codeLength {
+		return code
+	}
+	return code[:codeLength/2] + "-" + code[codeLength/2:]
+}
+
+// GetByCode returns the booking with the given confirmation code.
+// The code is normalized first, so it may be typed in any case and with separators.
+func (c *Conference) GetByCode(code string) (UserData, error) {
+	c.mu.Lock()
+	id, ok := c.codes[NormalizeConfirmationCode(code)]
+	c.mu.Unlock()
+
+	if !ok {
+		return UserData{}, ErrBookingNotFound
+	}
+	return c.store.Get(id)
+}
+
+// newConfirmationCode returns a random code not used by any booking of the conference.
+// Callers must hold c.mu.
+func (c *Conference) newConfirmationCode() (string, error) {
+	for attempt := 0; attempt < maxCodeAttempts; attempt++ {
+		code, err := randomCode()
+		if err != nil {
+			return "", err
+		}
+		if _, taken := c.codes[code]; !taken {
+			return code, nil
+		}
+	}
+	return "", ErrNoConfirmationCode
+}
+
+// randomCode draws codeLength characters from codeAlphabet
+func randomCode() (string, error) {
+	max := big.NewInt(int64(len(codeAlphabet)))
+	code := make([]byte, codeLength)
+	for i := range code {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		code[i] = codeAlphabet[n.Int64()]
+	}
+	return string(code), nil
+}
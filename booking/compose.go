@@ -0,0 +1,526 @@
+package booking
+
+import "unicode"
+
+// composeLatin replaces every Latin letter followed by a combining mark with
+// the precomposed letter, so "Zoe\u0308" becomes "Zoë". Marks are composed
+// with the letter right before them, which covers names typed in canonical
+// order; other scripts are left as they are.
+func composeLatin(s string) string {
+	composed := make([]rune, 0, len(s))
+	for _, r := range s {
+		if last := len(composed) - 1; last >= 0 && unicode.IsMark(r) {
+			if letter, ok := latinCompositions[[2]rune{composed[last], r}]; ok {
+				composed[last] = letter
+				continue
+			}
+		}
+		composed = append(composed, r)
+	}
+	return string(composed)
+}
+
+// latinCompositions maps a letter followed by a combining mark to the
+// precomposed letter, as Unicode canonical composition (NFC) does, for the
+// Latin-1 Supplement, Latin Extended-A and B and Latin Extended Additional
+// blocks. It was generated from the Unicode character database; the
+// standard library has no normalization tables of its own.
+var latinCompositions = map[[2]rune]rune{
+	{0x0041, 0x0300}: 0x00C0, // À
+	{0x0041, 0x0301}: 0x00C1, // Á
+	{0x0041, 0x0302}: 0x00C2, // Â
+	{0x0041, 0x0303}: 0x00C3, // Ã
+	{0x0041, 0x0304}: 0x0100, // Ā
+	{0x0041, 0x0306}: 0x0102, // Ă
+	{0x0041, 0x0307}: 0x0226, // Ȧ
+	{0x0041, 0x0308}: 0x00C4, // Ä
+	{0x0041, 0x0309}: 0x1EA2, // Ả
+	{0x0041, 0x030A}: 0x00C5, // Å
+	{0x0041, 0x030C}: 0x01CD, // Ǎ
+	{0x0041, 0x030F}: 0x0200, // Ȁ
+	{0x0041, 0x0311}: 0x0202, // Ȃ
+	{0x0041, 0x0323}: 0x1EA0, // Ạ
+	{0x0041, 0x0325}: 0x1E00, // Ḁ
+	{0x0041, 0x0328}: 0x0104, // Ą
+	{0x0042, 0x0307}: 0x1E02, // Ḃ
+	{0x0042, 0x0323}: 0x1E04, // Ḅ
+	{0x0042, 0x0331}: 0x1E06, // Ḇ
+	{0x0043, 0x0301}: 0x0106, // Ć
+	{0x0043, 0x0302}: 0x0108, // Ĉ
+	{0x0043, 0x0307}: 0x010A, // Ċ
+	{0x0043, 0x030C}: 0x010C, // Č
+	{0x0043, 0x0327}: 0x00C7, // Ç
+	{0x0044, 0x0307}: 0x1E0A, // Ḋ
+	{0x0044, 0x030C}: 0x010E, // Ď
+	{0x0044, 0x0323}: 0x1E0C, // Ḍ
+	{0x0044, 0x0327}: 0x1E10, // Ḑ
+	{0x0044, 0x032D}: 0x1E12, // Ḓ
+	{0x0044, 0x0331}: 0x1E0E, // Ḏ
+	{0x0045, 0x0300}: 0x00C8, // È
+	{0x0045, 0x0301}: 0x00C9, // É
+	{0x0045, 0x0302}: 0x00CA, // Ê
+	{0x0045, 0x0303}: 0x1EBC, // Ẽ
+	{0x0045, 0x0304}: 0x0112, // Ē
+	{0x0045, 0x0306}: 0x0114, // Ĕ
+	{0x0045, 0x0307}: 0x0116, // Ė
+	{0x0045, 0x0308}: 0x00CB, // Ë
+	{0x0045, 0x0309}: 0x1EBA, // Ẻ
+	{0x0045, 0x030C}: 0x011A, // Ě
+	{0x0045, 0x030F}: 0x0204, // Ȅ
+	{0x0045, 0x0311}: 0x0206, // Ȇ
+	{0x0045, 0x0323}: 0x1EB8, // Ẹ
+	{0x0045, 0x0327}: 0x0228, // Ȩ
+	{0x0045, 0x0328}: 0x0118, // Ę
+	{0x0045, 0x032D}: 0x1E18, // Ḙ
+	{0x0045, 0x0330}: 0x1E1A, // Ḛ
+	{0x0046, 0x0307}: 0x1E1E, // Ḟ
+	{0x0047, 0x0301}: 0x01F4, // Ǵ
+	{0x0047, 0x0302}: 0x011C, // Ĝ
+	{0x0047, 0x0304}: 0x1E20, // Ḡ
+	{0x0047, 0x0306}: 0x011E, // Ğ
+	{0x0047, 0x0307}: 0x0120, // Ġ
+	{0x0047, 0x030C}: 0x01E6, // Ǧ
+	{0x0047, 0x0327}: 0x0122, // Ģ
+	{0x0048, 0x0302}: 0x0124, // Ĥ
+	{0x0048, 0x0307}: 0x1E22, // Ḣ
+	{0x0048, 0x0308}: 0x1E26, // Ḧ
+	{0x0048, 0x030C}: 0x021E, // Ȟ
+	{0x0048, 0x0323}: 0x1E24, // Ḥ
+	{0x0048, 0x0327}: 0x1E28, // Ḩ
+	{0x0048, 0x032E}: 0x1E2A, // Ḫ
+	{0x0049, 0x0300}: 0x00CC, // Ì
+	{0x0049, 0x0301}: 0x00CD, // Í
+	{0x0049, 0x0302}: 0x00CE, // Î
+	{0x0049, 0x0303}: 0x0128, // Ĩ
+	{0x0049, 0x0304}: 0x012A, // Ī
+	{0x0049, 0x0306}: 0x012C, // Ĭ
+	{0x0049, 0x0307}: 0x0130, // İ
+	{0x0049, 0x0308}: 0x00CF, // Ï
+	{0x0049, 0x0309}: 0x1EC8, // Ỉ
+	{0x0049, 0x030C}: 0x01CF, // Ǐ
+	{0x0049, 0x030F}: 0x0208, // Ȉ
+	{0x0049, 0x0311}: 0x020A, // Ȋ
+	{0x0049, 0x0323}: 0x1ECA, // Ị
+	{0x0049, 0x0328}: 0x012E, // Į
+	{0x0049, 0x0330}: 0x1E2C, // Ḭ
+	{0x004A, 0x0302}: 0x0134, // Ĵ
+	{0x004B, 0x0301}: 0x1E30, // Ḱ
+	{0x004B, 0x030C}: 0x01E8, // Ǩ
+	{0x004B, 0x0323}: 0x1E32, // Ḳ
+	{0x004B, 0x0327}: 0x0136, // Ķ
+	{0x004B, 0x0331}: 0x1E34, // Ḵ
+	{0x004C, 0x0301}: 0x0139, // Ĺ
+	{0x004C, 0x030C}: 0x013D, // Ľ
+	{0x004C, 0x0323}: 0x1E36, // Ḷ
+	{0x004C, 0x0327}: 0x013B, // Ļ
+	{0x004C, 0x032D}: 0x1E3C, // Ḽ
+	{0x004C, 0x0331}: 0x1E3A, // Ḻ
+	{0x004D, 0x0301}: 0x1E3E, // Ḿ
+	{0x004D, 0x0307}: 0x1E40, // Ṁ
+	{0x004D, 0x0323}: 0x1E42, // Ṃ
+	{0x004E, 0x0300}: 0x01F8, // Ǹ
+	{0x004E, 0x0301}: 0x0143, // Ń
+	{0x004E, 0x0303}: 0x00D1, // Ñ
+	{0x004E, 0x0307}: 0x1E44, // Ṅ
+	{0x004E, 0x030C}: 0x0147, // Ň
+	{0x004E, 0x0323}: 0x1E46, // Ṇ
+	{0x004E, 0x0327}: 0x0145, // Ņ
+	{0x004E, 0x032D}: 0x1E4A, // Ṋ
+	{0x004E, 0x0331}: 0x1E48, // Ṉ
+	{0x004F, 0x0300}: 0x00D2, // Ò
+	{0x004F, 0x0301}: 0x00D3, // Ó
+	{0x004F, 0x0302}: 0x00D4, // Ô
+	{0x004F, 0x0303}: 0x00D5, // Õ
+	{0x004F, 0x0304}: 0x014C, // Ō
+	{0x004F, 0x0306}: 0x014E, // Ŏ
+	{0x004F, 0x0307}: 0x022E, // Ȯ
+	{0x004F, 0x0308}: 0x00D6, // Ö
+	{0x004F, 0x0309}: 0x1ECE, // Ỏ
+	{0x004F, 0x030B}: 0x0150, // Ő
+	{0x004F, 0x030C}: 0x01D1, // Ǒ
+	{0x004F, 0x030F}: 0x020C, // Ȍ
+	{0x004F, 0x0311}: 0x020E, // Ȏ
+	{0x004F, 0x031B}: 0x01A0, // Ơ
+	{0x004F, 0x0323}: 0x1ECC, // Ọ
+	{0x004F, 0x0328}: 0x01EA, // Ǫ
+	{0x0050, 0x0301}: 0x1E54, // Ṕ
+	{0x0050, 0x0307}: 0x1E56, // Ṗ
+	{0x0052, 0x0301}: 0x0154, // Ŕ
+	{0x0052, 0x0307}: 0x1E58, // Ṙ
+	{0x0052, 0x030C}: 0x0158, // Ř
+	{0x0052, 0x030F}: 0x0210, // Ȑ
+	{0x0052, 0x0311}: 0x0212, // Ȓ
+	{0x0052, 0x0323}: 0x1E5A, // Ṛ
+	{0x0052, 0x0327}: 0x0156, // Ŗ
+	{0x0052, 0x0331}: 0x1E5E, // Ṟ
+	{0x0053, 0x0301}: 0x015A, // Ś
+	{0x0053, 0x0302}: 0x015C, // Ŝ
+	{0x0053, 0x0307}: 0x1E60, // Ṡ
+	{0x0053, 0x030C}: 0x0160, // Š
+	{0x0053, 0x0323}: 0x1E62, // Ṣ
+	{0x0053, 0x0326}: 0x0218, // Ș
+	{0x0053, 0x0327}: 0x015E, // Ş
+	{0x0054, 0x0307}: 0x1E6A, // Ṫ
+	{0x0054, 0x030C}: 0x0164, // Ť
+	{0x0054, 0x0323}: 0x1E6C, // Ṭ
+	{0x0054, 0x0326}: 0x021A, // Ț
+	{0x0054, 0x0327}: 0x0162, // Ţ
+	{0x0054, 0x032D}: 0x1E70, // Ṱ
+	{0x0054, 0x0331}: 0x1E6E, // Ṯ
+	{0x0055, 0x0300}: 0x00D9, // Ù
+	{0x0055, 0x0301}: 0x00DA, // Ú
+	{0x0055, 0x0302}: 0x00DB, // Û
+	{0x0055, 0x0303}: 0x0168, // Ũ
+	{0x0055, 0x0304}: 0x016A, // Ū
+	{0x0055, 0x0306}: 0x016C, // Ŭ
+	{0x0055, 0x0308}: 0x00DC, // Ü
+	{0x0055, 0x0309}: 0x1EE6, // Ủ
+	{0x0055, 0x030A}: 0x016E, // Ů
+	{0x0055, 0x030B}: 0x0170, // Ű
+	{0x0055, 0x030C}: 0x01D3, // Ǔ
+	{0x0055, 0x030F}: 0x0214, // Ȕ
+	{0x0055, 0x0311}: 0x0216, // Ȗ
+	{0x0055, 0x031B}: 0x01AF, // Ư
+	{0x0055, 0x0323}: 0x1EE4, // Ụ
+	{0x0055, 0x0324}: 0x1E72, // Ṳ
+	{0x0055, 0x0328}: 0x0172, // Ų
+	{0x0055, 0x032D}: 0x1E76, // Ṷ
+	{0x0055, 0x0330}: 0x1E74, // Ṵ
+	{0x0056, 0x0303}: 0x1E7C, // Ṽ
+	{0x0056, 0x0323}: 0x1E7E, // Ṿ
+	{0x0057, 0x0300}: 0x1E80, // Ẁ
+	{0x0057, 0x0301}: 0x1E82, // Ẃ
+	{0x0057, 0x0302}: 0x0174, // Ŵ
+	{0x0057, 0x0307}: 0x1E86, // Ẇ
+	{0x0057, 0x0308}: 0x1E84, // Ẅ
+	{0x0057, 0x0323}: 0x1E88, // Ẉ
+	{0x0058, 0x0307}: 0x1E8A, // Ẋ
+	{0x0058, 0x0308}: 0x1E8C, // Ẍ
+	{0x0059, 0x0300}: 0x1EF2, // Ỳ
+	{0x0059, 0x0301}: 0x00DD, // Ý
+	{0x0059, 0x0302}: 0x0176, // Ŷ
+	{0x0059, 0x0303}: 0x1EF8, // Ỹ
+	{0x0059, 0x0304}: 0x0232, // Ȳ
+	{0x0059, 0x0307}: 0x1E8E, // Ẏ
+	{0x0059, 0x0308}: 0x0178, // Ÿ
+	{0x0059, 0x0309}: 0x1EF6, // Ỷ
+	{0x0059, 0x0323}: 0x1EF4, // Ỵ
+	{0x005A, 0x0301}: 0x0179, // Ź
+	{0x005A, 0x0302}: 0x1E90, // Ẑ
+	{0x005A, 0x0307}: 0x017B, // Ż
+	{0x005A, 0x030C}: 0x017D, // Ž
+	{0x005A, 0x0323}: 0x1E92, // Ẓ
+	{0x005A, 0x0331}: 0x1E94, // Ẕ
+	{0x0061, 0x0300}: 0x00E0, // à
+	{0x0061, 0x0301}: 0x00E1, // á
+	{0x0061, 0x0302}: 0x00E2, // â
+	{0x0061, 0x0303}: 0x00E3, // ã
+	{0x0061, 0x0304}: 0x0101, // ā
+	{0x0061, 0x0306}: 0x0103, // ă
+	{0x0061, 0x0307}: 0x0227, // ȧ
+	{0x0061, 0x0308}: 0x00E4, // ä
+	{0x0061, 0x0309}: 0x1EA3, // ả
+	{0x0061, 0x030A}: 0x00E5, // å
+	{0x0061, 0x030C}: 0x01CE, // ǎ
+	{0x0061, 0x030F}: 0x0201, // ȁ
+	{0x0061, 0x0311}: 0x0203, // ȃ
+	{0x0061, 0x0323}: 0x1EA1, // ạ
+	{0x0061, 0x0325}: 0x1E01, // ḁ
+	{0x0061, 0x0328}: 0x0105, // ą
+	{0x0062, 0x0307}: 0x1E03, // ḃ
+	{0x0062, 0x0323}: 0x1E05, // ḅ
+	{0x0062, 0x0331}: 0x1E07, // ḇ
+	{0x0063, 0x0301}: 0x0107, // ć
+	{0x0063, 0x0302}: 0x0109, // ĉ
+	{0x0063, 0x0307}: 0x010B, // ċ
+	{0x0063, 0x030C}: 0x010D, // č
+	{0x0063, 0x0327}: 0x00E7, // ç
+	{0x0064, 0x0307}: 0x1E0B, // ḋ
+	{0x0064, 0x030C}: 0x010F, // ď
+	{0x0064, 0x0323}: 0x1E0D, // ḍ
+	{0x0064, 0x0327}: 0x1E11, // ḑ
+	{0x0064, 0x032D}: 0x1E13, // ḓ
+	{0x0064, 0x0331}: 0x1E0F, // ḏ
+	{0x0065, 0x0300}: 0x00E8, // è
+	{0x0065, 0x0301}: 0x00E9, // é
+	{0x0065, 0x0302}: 0x00EA, // ê
+	{0x0065, 0x0303}: 0x1EBD, // ẽ
+	{0x0065, 0x0304}: 0x0113, // ē
+	{0x0065, 0x0306}: 0x0115, // ĕ
+	{0x0065, 0x0307}: 0x0117, // ė
+	{0x0065, 0x0308}: 0x00EB, // ë
+	{0x0065, 0x0309}: 0x1EBB, // ẻ
+	{0x0065, 0x030C}: 0x011B, // ě
+	{0x0065, 0x030F}: 0x0205, // ȅ
+	{0x0065, 0x0311}: 0x0207, // ȇ
+	{0x0065, 0x0323}: 0x1EB9, // ẹ
+	{0x0065, 0x0327}: 0x0229, // ȩ
+	{0x0065, 0x0328}: 0x0119, // ę
+	{0x0065, 0x032D}: 0x1E19, // ḙ
+	{0x0065, 0x0330}: 0x1E1B, // ḛ
+	{0x0066, 0x0307}: 0x1E1F, // ḟ
+	{0x0067, 0x0301}: 0x01F5, // ǵ
+	{0x0067, 0x0302}: 0x011D, // ĝ
+	{0x0067, 0x0304}: 0x1E21, // ḡ
+	{0x0067, 0x0306}: 0x011F, // ğ
+	{0x0067, 0x0307}: 0x0121, // ġ
+	{0x0067, 0x030C}: 0x01E7, // ǧ
+	{0x0067, 0x0327}: 0x0123, // ģ
+	{0x0068, 0x0302}: 0x0125, // ĥ
+	{0x0068, 0x0307}: 0x1E23, // ḣ
+	{0x0068, 0x0308}: 0x1E27, // ḧ
+	{0x0068, 0x030C}: 0x021F, // ȟ
+	{0x0068, 0x0323}: 0x1E25, // ḥ
+	{0x0068, 0x0327}: 0x1E29, // ḩ
+	{0x0068, 0x032E}: 0x1E2B, // ḫ
+	{0x0068, 0x0331}: 0x1E96, // ẖ
+	{0x0069, 0x0300}: 0x00EC, // ì
+	{0x0069, 0x0301}: 0x00ED, // í
+	{0x0069, 0x0302}: 0x00EE, // î
+	{0x0069, 0x0303}: 0x0129, // ĩ
+	{0x0069, 0x0304}: 0x012B, // ī
+	{0x0069, 0x0306}: 0x012D, // ĭ
+	{0x0069, 0x0308}: 0x00EF, // ï
+	{0x0069, 0x0309}: 0x1EC9, // ỉ
+	{0x0069, 0x030C}: 0x01D0, // ǐ
+	{0x0069, 0x030F}: 0x0209, // ȉ
+	{0x0069, 0x0311}: 0x020B, // ȋ
+	{0x0069, 0x0323}: 0x1ECB, // ị
+	{0x0069, 0x0328}: 0x012F, // į
+	{0x0069, 0x0330}: 0x1E2D, // ḭ
+	{0x006A, 0x0302}: 0x0135, // ĵ
+	{0x006A, 0x030C}: 0x01F0, // ǰ
+	{0x006B, 0x0301}: 0x1E31, // ḱ
+	{0x006B, 0x030C}: 0x01E9, // ǩ
+	{0x006B, 0x0323}: 0x1E33, // ḳ
+	{0x006B, 0x0327}: 0x0137, // ķ
+	{0x006B, 0x0331}: 0x1E35, // ḵ
+	{0x006C, 0x0301}: 0x013A, // ĺ
+	{0x006C, 0x030C}: 0x013E, // ľ
+	{0x006C, 0x0323}: 0x1E37, // ḷ
+	{0x006C, 0x0327}: 0x013C, // ļ
+	{0x006C, 0x032D}: 0x1E3D, // ḽ
+	{0x006C, 0x0331}: 0x1E3B, // ḻ
+	{0x006D, 0x0301}: 0x1E3F, // ḿ
+	{0x006D, 0x0307}: 0x1E41, // ṁ
+	{0x006D, 0x0323}: 0x1E43, // ṃ
+	{0x006E, 0x0300}: 0x01F9, // ǹ
+	{0x006E, 0x0301}: 0x0144, // ń
+	{0x006E, 0x0303}: 0x00F1, // ñ
+	{0x006E, 0x0307}: 0x1E45, // ṅ
+	{0x006E, 0x030C}: 0x0148, // ň
+	{0x006E, 0x0323}: 0x1E47, // ṇ
+	{0x006E, 0x0327}: 0x0146, // ņ
+	{0x006E, 0x032D}: 0x1E4B, // ṋ
+	{0x006E, 0x0331}: 0x1E49, // ṉ
+	{0x006F, 0x0300}: 0x00F2, // ò
+	{0x006F, 0x0301}: 0x00F3, // ó
+	{0x006F, 0x0302}: 0x00F4, // ô
+	{0x006F, 0x0303}: 0x00F5, // õ
+	{0x006F, 0x0304}: 0x014D, // ō
+	{0x006F, 0x0306}: 0x014F, // ŏ
+	{0x006F, 0x0307}: 0x022F, // ȯ
+	{0x006F, 0x0308}: 0x00F6, // ö
+	{0x006F, 0x0309}: 0x1ECF, // ỏ
+	{0x006F, 0x030B}: 0x0151, // ő
+	{0x006F, 0x030C}: 0x01D2, // ǒ
+	{0x006F, 0x030F}: 0x020D, // ȍ
+	{0x006F, 0x0311}: 0x020F, // ȏ
+	{0x006F, 0x031B}: 0x01A1, // ơ
+	{0x006F, 0x0323}: 0x1ECD, // ọ
+	{0x006F, 0x0328}: 0x01EB, // ǫ
+	{0x0070, 0x0301}: 0x1E55, // ṕ
+	{0x0070, 0x0307}: 0x1E57, // ṗ
+	{0x0072, 0x0301}: 0x0155, // ŕ
+	{0x0072, 0x0307}: 0x1E59, // ṙ
+	{0x0072, 0x030C}: 0x0159, // ř
+	{0x0072, 0x030F}: 0x0211, // ȑ
+	{0x0072, 0x0311}: 0x0213, // ȓ
+	{0x0072, 0x0323}: 0x1E5B, // ṛ
+	{0x0072, 0x0327}: 0x0157, // ŗ
+	{0x0072, 0x0331}: 0x1E5F, // ṟ
+	{0x0073, 0x0301}: 0x015B, // ś
+	{0x0073, 0x0302}: 0x015D, // ŝ
+	{0x0073, 0x0307}: 0x1E61, // ṡ
+	{0x0073, 0x030C}: 0x0161, // š
+	{0x0073, 0x0323}: 0x1E63, // ṣ
+	{0x0073, 0x0326}: 0x0219, // ș
+	{0x0073, 0x0327}: 0x015F, // ş
+	{0x0074, 0x0307}: 0x1E6B, // ṫ
+	{0x0074, 0x0308}: 0x1E97, // ẗ
+	{0x0074, 0x030C}: 0x0165, // ť
+	{0x0074, 0x0323}: 0x1E6D, // ṭ
+	{0x0074, 0x0326}: 0x021B, // ț
+	{0x0074, 0x0327}: 0x0163, // ţ
+	{0x0074, 0x032D}: 0x1E71, // ṱ
+	{0x0074, 0x0331}: 0x1E6F, // ṯ
+	{0x0075, 0x0300}: 0x00F9, // ù
+	{0x0075, 0x0301}: 0x00FA, // ú
+	{0x0075, 0x0302}: 0x00FB, // û
+	{0x0075, 0x0303}: 0x0169, // ũ
+	{0x0075, 0x0304}: 0x016B, // ū
+	{0x0075, 0x0306}: 0x016D, // ŭ
+	{0x0075, 0x0308}: 0x00FC, // ü
+	{0x0075, 0x0309}: 0x1EE7, // ủ
+	{0x0075, 0x030A}: 0x016F, // ů
+	{0x0075, 0x030B}: 0x0171, // ű
+	{0x0075, 0x030C}: 0x01D4, // ǔ
+	{0x0075, 0x030F}: 0x0215, // ȕ
+	{0x0075, 0x0311}: 0x0217, // ȗ
+	{0x0075, 0x031B}: 0x01B0, // ư
+	{0x0075, 0x0323}: 0x1EE5, // ụ
+	{0x0075, 0x0324}: 0x1E73, // ṳ
+	{0x0075, 0x0328}: 0x0173, // ų
+	{0x0075, 0x032D}: 0x1E77, // ṷ
+	{0x0075, 0x0330}: 0x1E75, // ṵ
+	{0x0076, 0x0303}: 0x1E7D, // ṽ
+	{0x0076, 0x0323}: 0x1E7F, // ṿ
+	{0x0077, 0x0300}: 0x1E81, // ẁ
+	{0x0077, 0x0301}: 0x1E83, // ẃ
+	{0x0077, 0x0302}: 0x0175, // ŵ
+	{0x0077, 0x0307}: 0x1E87, // ẇ
+	{0x0077, 0x0308}: 0x1E85, // ẅ
+	{0x0077, 0x030A}: 0x1E98, // ẘ
+	{0x0077, 0x0323}: 0x1E89, // ẉ
+	{0x0078, 0x0307}: 0x1E8B, // ẋ
+	{0x0078, 0x0308}: 0x1E8D, // ẍ
+	{0x0079, 0x0300}: 0x1EF3, // ỳ
+	{0x0079, 0x0301}: 0x00FD, // ý
+	{0x0079, 0x0302}: 0x0177, // ŷ
+	{0x0079, 0x0303}: 0x1EF9, // ỹ
+	{0x0079, 0x0304}: 0x0233, // ȳ
+	{0x0079, 0x0307}: 0x1E8F, // ẏ
+	{0x0079, 0x0308}: 0x00FF, // ÿ
+	{0x0079, 0x0309}: 0x1EF7, // ỷ
+	{0x0079, 0x030A}: 0x1E99, // ẙ
+	{0x0079, 0x0323}: 0x1EF5, // ỵ
+	{0x007A, 0x0301}: 0x017A, // ź
+	{0x007A, 0x0302}: 0x1E91, // ẑ
+	{0x007A, 0x0307}: 0x017C, // ż
+	{0x007A, 0x030C}: 0x017E, // ž
+	{0x007A, 0x0323}: 0x1E93, // ẓ
+	{0x007A, 0x0331}: 0x1E95, // ẕ
+	{0x00C2, 0x0300}: 0x1EA6, // Ầ
+	{0x00C2, 0x0301}: 0x1EA4, // Ấ
+	{0x00C2, 0x0303}: 0x1EAA, // Ẫ
+	{0x00C2, 0x0309}: 0x1EA8, // Ẩ
+	{0x00C4, 0x0304}: 0x01DE, // Ǟ
+	{0x00C5, 0x0301}: 0x01FA, // Ǻ
+	{0x00C6, 0x0301}: 0x01FC, // Ǽ
+	{0x00C6, 0x0304}: 0x01E2, // Ǣ
+	{0x00C7, 0x0301}: 0x1E08, // Ḉ
+	{0x00CA, 0x0300}: 0x1EC0, // Ề
+	{0x00CA, 0x0301}: 0x1EBE, // Ế
+	{0x00CA, 0x0303}: 0x1EC4, // Ễ
+	{0x00CA, 0x0309}: 0x1EC2, // Ể
+	{0x00CF, 0x0301}: 0x1E2E, // Ḯ
+	{0x00D4, 0x0300}: 0x1ED2, // Ồ
+	{0x00D4, 0x0301}: 0x1ED0, // Ố
+	{0x00D4, 0x0303}: 0x1ED6, // Ỗ
+	{0x00D4, 0x0309}: 0x1ED4, // Ổ
+	{0x00D5, 0x0301}: 0x1E4C, // Ṍ
+	{0x00D5, 0x0304}: 0x022C, // Ȭ
+	{0x00D5, 0x0308}: 0x1E4E, // Ṏ
+	{0x00D6, 0x0304}: 0x022A, // Ȫ
+	{0x00D8, 0x0301}: 0x01FE, // Ǿ
+	{0x00DC, 0x0300}: 0x01DB, // Ǜ
+	{0x00DC, 0x0301}: 0x01D7, // Ǘ
+	{0x00DC, 0x0304}: 0x01D5, // Ǖ
+	{0x00DC, 0x030C}: 0x01D9, // Ǚ
+	{0x00E2, 0x0300}: 0x1EA7, // ầ
+	{0x00E2, 0x0301}: 0x1EA5, // ấ
+	{0x00E2, 0x0303}: 0x1EAB, // ẫ
+	{0x00E2, 0x0309}: 0x1EA9, // ẩ
+	{0x00E4, 0x0304}: 0x01DF, // ǟ
+	{0x00E5, 0x0301}: 0x01FB, // ǻ
+	{0x00E6, 0x0301}: 0x01FD, // ǽ
+	{0x00E6, 0x0304}: 0x01E3, // ǣ
+	{0x00E7, 0x0301}: 0x1E09, // ḉ
+	{0x00EA, 0x0300}: 0x1EC1, // ề
+	{0x00EA, 0x0301}: 0x1EBF, // ế
+	{0x00EA, 0x0303}: 0x1EC5, // ễ
+	{0x00EA, 0x0309}: 0x1EC3, // ể
+	{0x00EF, 0x0301}: 0x1E2F, // ḯ
+	{0x00F4, 0x0300}: 0x1ED3, // ồ
+	{0x00F4, 0x0301}: 0x1ED1, // ố
+	{0x00F4, 0x0303}: 0x1ED7, // ỗ
+	{0x00F4, 0x0309}: 0x1ED5, // ổ
+	{0x00F5, 0x0301}: 0x1E4D, // ṍ
+	{0x00F5, 0x0304}: 0x022D, // ȭ
+	{0x00F5, 0x0308}: 0x1E4F, // ṏ
+	{0x00F6, 0x0304}: 0x022B, // ȫ
+	{0x00F8, 0x0301}: 0x01FF, // ǿ
+	{0x00FC, 0x0300}: 0x01DC, // ǜ
+	{0x00FC, 0x0301}: 0x01D8, // ǘ
+	{0x00FC, 0x0304}: 0x01D6, // ǖ
+	{0x00FC, 0x030C}: 0x01DA, // ǚ
+	{0x0102, 0x0300}: 0x1EB0, // Ằ
+	{0x0102, 0x0301}: 0x1EAE, // Ắ
+	{0x0102, 0x0303}: 0x1EB4, // Ẵ
+	{0x0102, 0x0309}: 0x1EB2, // Ẳ
+	{0x0103, 0x0300}: 0x1EB1, // ằ
+	{0x0103, 0x0301}: 0x1EAF, // ắ
+	{0x0103, 0x0303}: 0x1EB5, // ẵ
+	{0x0103, 0x0309}: 0x1EB3, // ẳ
+	{0x0112, 0x0300}: 0x1E14, // Ḕ
+	{0x0112, 0x0301}: 0x1E16, // Ḗ
+	{0x0113, 0x0300}: 0x1E15, // ḕ
+	{0x0113, 0x0301}: 0x1E17, // ḗ
+	{0x014C, 0x0300}: 0x1E50, // Ṑ
+	{0x014C, 0x0301}: 0x1E52, // Ṓ
+	{0x014D, 0x0300}: 0x1E51, // ṑ
+	{0x014D, 0x0301}: 0x1E53, // ṓ
+	{0x015A, 0x0307}: 0x1E64, // Ṥ
+	{0x015B, 0x0307}: 0x1E65, // ṥ
+	{0x0160, 0x0307}: 0x1E66, // Ṧ
+	{0x0161, 0x0307}: 0x1E67, // ṧ
+	{0x0168, 0x0301}: 0x1E78, // Ṹ
+	{0x0169, 0x0301}: 0x1E79, // ṹ
+	{0x016A, 0x0308}: 0x1E7A, // Ṻ
+	{0x016B, 0x0308}: 0x1E7B, // ṻ
+	{0x017F, 0x0307}: 0x1E9B, // ẛ
+	{0x01A0, 0x0300}: 0x1EDC, // Ờ
+	{0x01A0, 0x0301}: 0x1EDA, // Ớ
+	{0x01A0, 0x0303}: 0x1EE0, // Ỡ
+	{0x01A0, 0x0309}: 0x1EDE, // Ở
+	{0x01A0, 0x0323}: 0x1EE2, // Ợ
+	{0x01A1, 0x0300}: 0x1EDD, // ờ
+	{0x01A1, 0x0301}: 0x1EDB, // ớ
+	{0x01A1, 0x0303}: 0x1EE1, // ỡ
+	{0x01A1, 0x0309}: 0x1EDF, // ở
+	{0x01A1, 0x0323}: 0x1EE3, // ợ
+	{0x01AF, 0x0300}: 0x1EEA, // Ừ
+	{0x01AF, 0x0301}: 0x1EE8, // Ứ
+	{0x01AF, 0x0303}: 0x1EEE, // Ữ
+	{0x01AF, 0x0309}: 0x1EEC, // Ử
+	{0x01AF, 0x0323}: 0x1EF0, // Ự
+	{0x01B0, 0x0300}: 0x1EEB, // ừ
+	{0x01B0, 0x0301}: 0x1EE9, // ứ
+	{0x01B0, 0x0303}: 0x1EEF, // ữ
+	{0x01B0, 0x0309}: 0x1EED, // ử
+	{0x01B0, 0x0323}: 0x1EF1, // ự
+	{0x01B7, 0x030C}: 0x01EE, // Ǯ
+	{0x01EA, 0x0304}: 0x01EC, // Ǭ
+	{0x01EB, 0x0304}: 0x01ED, // ǭ
+	{0x0226, 0x0304}: 0x01E0, // Ǡ
+	{0x0227, 0x0304}: 0x01E1, // ǡ
+	{0x0228, 0x0306}: 0x1E1C, // Ḝ
+	{0x0229, 0x0306}: 0x1E1D, // ḝ
+	{0x022E, 0x0304}: 0x0230, // Ȱ
+	{0x022F, 0x0304}: 0x0231, // ȱ
+	{0x0292, 0x030C}: 0x01EF, // ǯ
+	{0x1E36, 0x0304}: 0x1E38, // Ḹ
+	{0x1E37, 0x0304}: 0x1E39, // ḹ
+	{0x1E5A, 0x0304}: 0x1E5C, // Ṝ
+	{0x1E5B, 0x0304}: 0x1E5D, // ṝ
+	{0x1E62, 0x0307}: 0x1E68, // Ṩ
+	{0x1E63, 0x0307}: 0x1E69, // ṩ
+	{0x1EA0, 0x0302}: 0x1EAC, // Ậ
+	{0x1EA0, 0x0306}: 0x1EB6, // Ặ
+	{0x1EA1, 0x0302}: 0x1EAD, // ậ
+	{0x1EA1, 0x0306}: 0x1EB7, // ặ
+	{0x1EB8, 0x0302}: 0x1EC6, // Ệ
+	{0x1EB9, 0x0302}: 0x1EC7, // ệ
+	{0x1ECC, 0x0302}: 0x1ED8, // Ộ
+	{0x1ECD, 0x0302}: 0x1ED9, // ộ
+}
@@ -0,0 +1,219 @@
+package booking
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net/mail"
+	"os"
+	"strings"
+)
+
+// Length limits for email addresses from RFC 5321
+const (
+	maxEmailLength  = 254
+	maxLocalLength  = 64
+	maxDomainLength = 253
+	maxLabelLength  = 63
+)
+
+// ErrDisposableEmail is returned by ValidateEmail for addresses on the blocklist
+var ErrDisposableEmail = errors.New("email domain is not accepted")
+
+// EmailStrictness selects how strictly ValidateEmail checks an address
+type EmailStrictness int
+
+const (
+	// EmailStandard requires a dot-atom local part and a domain with a top-level domain
+	EmailStandard EmailStrictness = iota
+	// EmailLenient accepts anything net/mail.ParseAddress accepts as a bare address,
+	// including quoted local parts and domains without a dot
+	EmailLenient
+	// EmailStrict additionally limits the local part to letters, digits and ._%+-
+	// and requires an alphabetic top-level domain of at least two letters
+	EmailStrict
+)
+
+// ParseEmailStrictness converts "lenient", "standard" or "strict" to an EmailStrictness
+func ParseEmailStrictness(s string) (EmailStrictness, error) {
+	switch strings.ToLower(s) {
+	case "lenient":
+		return EmailLenient, nil
+	case "standard", "":
+		return EmailStandard, nil
+	case "strict":
+		return EmailStrict, nil
+	}
+	return EmailStandard, fmt.Errorf("unknown email strictness %q", s)
+}
+
+// EmailRules configures ValidateEmail.
+// The zero value checks addresses at EmailStandard without a blocklist.
+type EmailRules struct {
+	Strictness EmailStrictness
+	// Blocklist holds lower-case domains (e.g. disposable providers) whose
+	// addresses are rejected, including all of their subdomains
+	Blocklist map[string]bool
+}
+
+// LoadEmailBlocklist reads one domain per line from the file at path.
+// Blank lines and lines starting with '#' are ignored.
+func LoadEmailBlocklist(path string) (map[string]bool, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	blocklist := make(map[string]bool)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		blocklist[strings.ToLower(line)] = true
+	}
+	return blocklist, scanner.Err()
+}
+
+// ValidateEmail checks that email is a syntactically valid address under the rules.
+// It returns ErrDisposableEmail for blocked domains and a descriptive error otherwise.
+func ValidateEmail(email string, rules EmailRules) error {
+	if len(email) > maxEmailLength {
+		return fmt.Errorf("email address is longer than %v characters", maxEmailLength)
+	}
+
+	// ParseAddress also accepts "Name <addr>" forms, so insist on a bare address
+	address, err := mail.ParseAddress(email)
+	if err != nil || address.Name != "" || address.Address != email {
+		return errors.New("email address is not a valid address")
+	}
+
+	at := strings.LastIndex(email, "@")
+	local, domain := email[:at], email[at+1:]
+	if len(local) > maxLocalLength {
+		return fmt.Errorf("email local part is longer than %v characters", maxLocalLength)
+	}
+	if len(domain) > maxDomainLength {
+		return fmt.Errorf("email domain is longer than %v characters", maxDomainLength)
+	}
+
+	if rules.Strictness != EmailLenient {
+		if err := validateLocalPart(local, rules.Strictness); err != nil {
+			return err
+		}
+		if err := validateDomain(domain, rules.Strictness); err != nil {
+			return err
+		}
+	}
+
+	if isBlocked(strings.ToLower(domain), rules.Blocklist) {
+		return ErrDisposableEmail
+	}
+	return nil
+}
+
+// NormalizeEmail returns the form of an address used to recognise the same
+// mailbox: trimmed, lower-cased and without a "+tag" sub-address, so
+// "Ann+Conf@Example.com" and "ann@example.com" normalize to the same string
+func NormalizeEmail(email string) string {
+	email = strings.ToLower(strings.TrimSpace(email))
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return email
+	}
+	local, domain := email[:at], email[at+1:]
+	if plus := strings.Index(local, "+"); plus > 0 {
+		local = local[:plus]
+	}
+	return local + "@" + domain
+}
+
+// validateLocalPart checks the part before the '@' is a dot-atom
+func validateLocalPart(local string, strictness EmailStrictness) error {
+	if strings.HasPrefix(local, ".") || strings.HasSuffix(local, ".") || strings.Contains(local, "..") {
+		return errors.New("email local part has a misplaced dot")
+	}
+	for _, r := range local {
+		if isAlphaNumeric(r) || strings.ContainsRune("._%+-", r) {
+			continue
+		}
+		if strictness == EmailStandard && strings.ContainsRune("!#$&'*/=?^`{|}~", r) {
+			continue
+		}
+		return fmt.Errorf("email local part contains invalid character %q", r)
+	}
+	return nil
+}
+
+// validateDomain checks the part after the '@' is a host name with a top-level domain
+func validateDomain(domain string, strictness EmailStrictness) error {
+	labels := strings.Split(domain, ".")
+	if len(labels) < 2 {
+		return errors.New("email domain has no top-level domain")
+	}
+	for _, label := range labels {
+		if label == "" || len(label) > maxLabelLength {
+			return errors.New("email domain has an empty or too long label")
+		}
+		if strings.HasPrefix(label, "-") || strings.HasSuffix(label, "-") {
+			return errors.New("email domain label starts or ends with a hyphen")
+		}
+		for _, r := range label {
+			if !isAlphaNumeric(r) && r != '-' {
+				return fmt.Errorf("email domain contains invalid character %q", r)
+			}
+		}
+	}
+
+	tld := labels[len(labels)-1]
+	if strictness == EmailStrict {
+		if len(tld) < 2 {
+			return errors.New("email top-level domain is too short")
+		}
+		for _, r := range tld {
+			if !isLetter(r) {
+				return errors.New("email top-level domain must only contain letters")
+			}
+		}
+	} else if isNumeric(tld) {
+		return errors.New("email top-level domain must not be numeric")
+	}
+	return nil
+}
+
+// isBlocked reports whether domain or one of its parent domains is on the blocklist
+func isBlocked(domain string, blocklist map[string]bool) bool {
+	for domain != "" {
+		if blocklist[domain] {
+			return true
+		}
+		dot := strings.Index(domain, ".")
+		if dot < 0 {
+			break
+		}
+		domain = domain[dot+1:]
+	}
+	return false
+}
+
+// isLetter reports whether r is an ASCII letter
+func isLetter(r rune) bool {
+	return (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z')
+}
+
+// isAlphaNumeric reports whether r is an ASCII letter or digit
+func isAlphaNumeric(r rune) bool {
+	return isLetter(r) || (r >= '0' && r <= '9')
+}
+
+// isNumeric reports whether s only contains ASCII digits
+func isNumeric(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
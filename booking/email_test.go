@@ -0,0 +1,118 @@
+package booking
+
+import (
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestValidateEmail(t *testing.T) {
+	long := func(n int) string { return strings.Repeat("a", n) }
+	tests := []struct {
+		email                     string
+		lenient, standard, strict bool // whether each strictness accepts it
+	}{
+		{"ann@example.com", true, true, true},
+		{"ann.lee+conf@mail.example.co.uk", true, true, true},
+		{"@", false, false, false},
+		{"a@", false, false, false},
+		{"@example.com", false, false, false},
+		{"@@@", false, false, false},
+		{"ann@@example.com", false, false, false},
+		{"Ann <ann@example.com>", false, false, false},
+		{" ann@example.com", false, false, false},
+		{"ann@localhost", true, false, false},
+		{"ann@example.123", true, false, false},
+		{"ann@example.c", true, true, false},
+		{"ann@example.c0m", true, true, false},
+		{"ann@-example.com", true, false, false},
+		{"ann@exa_mple.com", true, false, false},
+		{"ann..lee@example.com", false, false, false},
+		{".ann@example.com", false, false, false},
+		{"ann!lee@example.com", true, true, false},
+		{long(64) + "@example.com", true, true, true},
+		{long(65) + "@example.com", false, false, false},
+		{"ann@" + long(63) + ".com", true, true, true},
+		{"ann@" + long(64) + ".com", true, false, false},
+		{long(64) + "@" + long(63) + "." + long(63) + "." + long(57) + ".com", true, true, true}, // 254 characters
+		{long(64) + "@" + long(63) + "." + long(63) + "." + long(58) + ".com", false, false, false},
+	}
+	for _, test := range tests {
+		for _, level := range []struct {
+			strictness EmailStrictness
+			valid      bool
+		}{{EmailLenient, test.lenient}, {EmailStandard, test.standard}, {EmailStrict, test.strict}} {
+			err := ValidateEmail(test.email, EmailRules{Strictness: level.strictness})
+			if (err == nil) != level.valid {
+				t.Errorf("ValidateEmail(%q) at strictness %v = %v, want valid %v", test.email, level.strictness, err, level.valid)
+			}
+		}
+	}
+}
+
+func TestValidateEmailBlocklist(t *testing.T) {
+	rules := EmailRules{Blocklist: map[string]bool{"mailinator.com": true}}
+	tests := []struct {
+		email   string
+		blocked bool
+	}{
+		{"ann@mailinator.com", true},
+		{"ann@MAILINATOR.com", true},
+		{"ann@eu.mx.mailinator.com", true},
+		{"ann@notmailinator.com", false},
+		{"ann@mailinator.com.example.org", false},
+		{"ann@example.com", false},
+	}
+	for _, test := range tests {
+		err := ValidateEmail(test.email, rules)
+		if blocked := errors.Is(err, ErrDisposableEmail); blocked != test.blocked {
+			t.Errorf("ValidateEmail(%q) = %v, want blocked %v", test.email, err, test.blocked)
+		}
+	}
+}
+
+func TestLoadEmailBlocklist(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "blocklist.txt")
+	data := "# disposable providers\nMailinator.com\n\n  temp-mail.org  \n"
+	if err := ioutil.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	blocklist, err := LoadEmailBlocklist(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(blocklist) != 2 || !blocklist["mailinator.com"] || !blocklist["temp-mail.org"] {
+		t.Errorf("LoadEmailBlocklist = %v, want mailinator.com and temp-mail.org", blocklist)
+	}
+	if _, err := LoadEmailBlocklist(filepath.Join(t.TempDir(), "missing.txt")); err == nil {
+		t.Error("LoadEmailBlocklist of a missing file succeeded")
+	}
+}
+
+func TestParseEmailStrictness(t *testing.T) {
+	for s, want := range map[string]EmailStrictness{"": EmailStandard, "lenient": EmailLenient, "Standard": EmailStandard, "STRICT": EmailStrict} {
+		if got, err := ParseEmailStrictness(s); err != nil || got != want {
+			t.Errorf("ParseEmailStrictness(%q) = %v, %v, want %v", s, got, err, want)
+		}
+	}
+	if _, err := ParseEmailStrictness("paranoid"); err == nil {
+		t.Error(`ParseEmailStrictness("paranoid") succeeded`)
+	}
+}
+
+func TestNormalizeEmail(t *testing.T) {
+	tests := map[string]string{
+		"ann@example.com":        "ann@example.com",
+		" Ann+Conf@Example.COM ": "ann@example.com",
+		"ann+a+b@example.com":    "ann@example.com",
+		"+tag@example.com":       "+tag@example.com",
+		"not-an-address":         "not-an-address",
+	}
+	for email, want := range tests {
+		if got := NormalizeEmail(email); got != want {
+			t.Errorf("NormalizeEmail(%q) = %q, want %q", email, got, want)
+		}
+	}
+}
@@ -0,0 +1,264 @@
+package booking
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ExportFormat selects how Export writes bookings
+type ExportFormat int
+
+const (
+	// ExportCSV writes a header row and one row per booking
+	ExportCSV ExportFormat = iota
+	// ExportJSON writes an indented JSON array of bookings
+	ExportJSON
+	// ExportNDJSON writes one JSON object per line
+	ExportNDJSON
+)
+
+// ParseExportFormat converts "csv", "json" or "ndjson" to an ExportFormat
+func ParseExportFormat(s string) (ExportFormat, error) {
+	switch strings.ToLower(s) {
+	case "csv", "":
+		return ExportCSV, nil
+	case "json":
+		return ExportJSON, nil
+	case "ndjson", "jsonl":
+		return ExportNDJSON, nil
+	}
+	return ExportCSV, fmt.Errorf("unknown export format %q", s)
+}
+
+// ExportFields lists the fields Export can write, in their default order.
+// They are named like the JSON fields of UserData; amounts are in minor units.
+var ExportFields = []string{
+	"id", "confirmationCode", "firstName", "lastName", "email", "numberOfTickets",
+	"lineItems", "subtotal", "promoCode", "discount", "total", "currency", "bookedAt",
+}
+
+// ExportOptions configures Export. The zero value writes every field of
+// every booking as CSV.
+type ExportOptions struct {
+	Format ExportFormat
+	// Fields are written in the given order; empty means ExportFields
+	Fields []string
+	// EmailDomain only exports bookings with an address on this domain
+	EmailDomain string
+	// MinTickets and MaxTickets only export bookings with at least and at
+	// most this many tickets; 0 means no limit
+	MinTickets uint
+	MaxTickets uint
+}
+
+// Export writes the bookings that pass the filters of opts to w.
+// Every field must be one of ExportFields.
+func Export(w io.Writer, bookings []UserData, opts ExportOptions) error {
+	fields := opts.Fields
+	if len(fields) == 0 {
+		fields = ExportFields
+	}
+	for _, field := range fields {
+		if !isExportField(field) {
+			return fmt.Errorf("booking: unknown export field %q", field)
+		}
+	}
+
+	var selected []UserData
+	for _, booking := range bookings {
+		if opts.matches(booking) {
+			selected = append(selected, booking)
+		}
+	}
+
+	switch opts.Format {
+	case ExportJSON:
+		return exportJSON(w, selected, fields)
+	case ExportNDJSON:
+		return exportNDJSON(w, selected, fields)
+	}
+	return exportCSV(w, selected, fields)
+}
+
+// Export writes the bookings of the conference to w (see Export)
+func (c *Conference) Export(w io.Writer, opts ExportOptions) error {
+	bookings, err := c.store.List()
+	if err != nil {
+		return err
+	}
+	return Export(w, bookings, opts)
+}
+
+// matches reports whether the booking passes the filters
+func (opts ExportOptions) matches(booking UserData) bool {
+	if opts.EmailDomain != "" {
+		at := strings.LastIndex(booking.Email, "@")
+		if at < 0 || !strings.EqualFold(booking.Email[at+1:], strings.TrimPrefix(opts.EmailDomain, "@")) {
+			return false
+		}
+	}
+	if opts.MinTickets > 0 && booking.NumberOfTickets < opts.MinTickets {
+		return false
+	}
+	if opts.MaxTickets > 0 && booking.NumberOfTickets > opts.MaxTickets {
+		return false
+	}
+	return true
+}
+
+// isExportField reports whether field is one of ExportFields
+func isExportField(field string) bool {
+	for _, known := range ExportFields {
+		if field == known {
+			return true
+		}
+	}
+	return false
+}
+
+// exportValue returns the value of a field as it is written to JSON
+func exportValue(booking UserData, field string) interface{} {
+	switch field {
+	case "id":
+		return booking.ID
+	case "confirmationCode":
+		return booking.ConfirmationCode
+	case "firstName":
+		return booking.FirstName
+	case "lastName":
+		return booking.LastName
+	case "email":
+		return booking.Email
+	case "numberOfTickets":
+		return booking.NumberOfTickets
+	case "lineItems":
+		return booking.LineItems
+	case "subtotal":
+		return booking.Subtotal
+	case "promoCode":
+		return booking.PromoCode
+	case "discount":
+		return booking.Discount
+	case "total":
+		return booking.Total
+	case "currency":
+		return booking.Currency
+	case "bookedAt":
+		if booking.BookedAt.IsZero() {
+			return nil
+		}
+		return booking.BookedAt
+	}
+	return nil
+}
+
+// exportText formats the value of a field for a CSV cell. Line items are
+// written as "2 x general; 1 x vip".
+func exportText(booking UserData, field string) string {
+	switch value := exportValue(booking, field).(type) {
+	case nil:
+		return ""
+	case string:
+		return value
+	case uint:
+		return strconv.FormatUint(uint64(value), 10)
+	case int64:
+		return strconv.FormatInt(value, 10)
+	case time.Time:
+		return value.Format(time.RFC3339)
+	case []LineItem:
+		items := make([]string, 0, len(value))
+		for _, item := range value {
+			items = append(items, fmt.Sprintf("%v x %v", item.Quantity, item.Tier))
+		}
+		return strings.Join(items, "; ")
+	default:
+		return fmt.Sprint(value)
+	}
+}
+
+// exportObject encodes the selected fields of a booking as a JSON object,
+// keeping the order of the fields
+func exportObject(booking UserData, fields []string) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, field := range fields {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		value, err := json.Marshal(exportValue(booking, field))
+		if err != nil {
+			return nil, err
+		}
+		fmt.Fprintf(&buf, "%q:", field)
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// exportCSV writes a header row with the field names and one row per booking
+func exportCSV(w io.Writer, bookings []UserData, fields []string) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(fields); err != nil {
+		return err
+	}
+	record := make([]string, len(fields))
+	for _, booking := range bookings {
+		for i, field := range fields {
+			record[i] = exportText(booking, field)
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// exportJSON writes the bookings as an indented JSON array
+func exportJSON(w io.Writer, bookings []UserData, fields []string) error {
+	var buf bytes.Buffer
+	buf.WriteByte('[')
+	for i, booking := range bookings {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		object, err := exportObject(booking, fields)
+		if err != nil {
+			return err
+		}
+		buf.Write(object)
+	}
+	buf.WriteByte(']')
+
+	var indented bytes.Buffer
+	if err := json.Indent(&indented, buf.Bytes(), "", "  "); err != nil {
+		return err
+	}
+	indented.WriteByte('\n')
+	_, err := indented.WriteTo(w)
+	return err
+}
+
+// exportNDJSON writes one JSON object per line, so large exports can be
+// processed line by line
+func exportNDJSON(w io.Writer, bookings []UserData, fields []string) error {
+	writer := bufio.NewWriter(w)
+	for _, booking := range bookings {
+		object, err := exportObject(booking, fields)
+		if err != nil {
+			return err
+		}
+		writer.Write(object)
+		writer.WriteByte('\n')
+	}
+	return writer.Flush()
+}
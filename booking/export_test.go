@@ -0,0 +1,195 @@
+package booking
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+// exportBookings are three bookings on two domains with 1, 2 and 4 tickets
+func exportBookings() []UserData {
+	return []UserData{
+		{ID: 1, ConfirmationCode: "AAAA1111", FirstName: "Ada", LastName: "Lovelace", Email: "ada@example.com", NumberOfTickets: 1,
+			LineItems: []LineItem{{Tier: "general", Quantity: 1, UnitPrice: 5000, Total: 5000}},
+			Subtotal:  5000, Total: 5000, Currency: "EUR", BookedAt: time.Date(2024, 3, 1, 9, 30, 0, 0, time.UTC)},
+		{ID: 2, ConfirmationCode: "BBBB2222", FirstName: "Alan", LastName: "Turing, Jr.", Email: "alan@Other.org", NumberOfTickets: 2,
+			LineItems: []LineItem{{Tier: "general", Quantity: 1, UnitPrice: 5000, Total: 5000}, {Tier: "vip", Quantity: 1, UnitPrice: 9000, Total: 9000}},
+			Subtotal:  14000, PromoCode: "SPRING", Discount: 1400, Total: 12600, Currency: "EUR"},
+		{ID: 3, ConfirmationCode: "CCCC3333", FirstName: "Grace", LastName: "Hopper", Email: "grace@example.com", NumberOfTickets: 4,
+			LineItems: []LineItem{{Tier: "general", Quantity: 4, UnitPrice: 5000, Total: 20000}},
+			Subtotal:  20000, Total: 20000, Currency: "EUR", BookedAt: time.Date(2024, 3, 2, 14, 0, 0, 0, time.UTC)},
+	}
+}
+
+func TestExport(t *testing.T) {
+	tests := []struct {
+		name   string
+		opts   ExportOptions
+		csv    string
+		json   string
+		ndjson string
+	}{
+		{
+			name: "fields in the given order",
+			opts: ExportOptions{Fields: []string{"email", "id", "lastName"}},
+			csv:  "email,id,lastName\nada@example.com,1,Lovelace\nalan@Other.org,2,\"Turing, Jr.\"\ngrace@example.com,3,Hopper\n",
+			json: `[
+  {
+    "email": "ada@example.com",
+    "id": 1,
+    "lastName": "Lovelace"
+  },
+  {
+    "email": "alan@Other.org",
+    "id": 2,
+    "lastName": "Turing, Jr."
+  },
+  {
+    "email": "grace@example.com",
+    "id": 3,
+    "lastName": "Hopper"
+  }
+]
+`,
+			ndjson: `{"email":"ada@example.com","id":1,"lastName":"Lovelace"}
+{"email":"alan@Other.org","id":2,"lastName":"Turing, Jr."}
+{"email":"grace@example.com","id":3,"lastName":"Hopper"}
+`,
+		},
+		{
+			name: "line items, amounts and dates",
+			opts: ExportOptions{Fields: []string{"id", "lineItems", "promoCode", "discount", "total", "bookedAt"}, MinTickets: 2, MaxTickets: 2},
+			csv:  "id,lineItems,promoCode,discount,total,bookedAt\n2,1 x general; 1 x vip,SPRING,1400,12600,\n",
+			json: `[
+  {
+    "id": 2,
+    "lineItems": [
+      {
+        "tier": "general",
+        "quantity": 1,
+        "unitPrice": 5000,
+        "total": 5000
+      },
+      {
+        "tier": "vip",
+        "quantity": 1,
+        "unitPrice": 9000,
+        "total": 9000
+      }
+    ],
+    "promoCode": "SPRING",
+    "discount": 1400,
+    "total": 12600,
+    "bookedAt": null
+  }
+]
+`,
+			ndjson: `{"id":2,"lineItems":[{"tier":"general","quantity":1,"unitPrice":5000,"total":5000},{"tier":"vip","quantity":1,"unitPrice":9000,"total":9000}],"promoCode":"SPRING","discount":1400,"total":12600,"bookedAt":null}
+`,
+		},
+		{
+			name:   "email domain",
+			opts:   ExportOptions{Fields: []string{"id", "bookedAt"}, EmailDomain: "@EXAMPLE.com"},
+			csv:    "id,bookedAt\n1,2024-03-01T09:30:00Z\n3,2024-03-02T14:00:00Z\n",
+			json:   "[\n  {\n    \"id\": 1,\n    \"bookedAt\": \"2024-03-01T09:30:00Z\"\n  },\n  {\n    \"id\": 3,\n    \"bookedAt\": \"2024-03-02T14:00:00Z\"\n  }\n]\n",
+			ndjson: "{\"id\":1,\"bookedAt\":\"2024-03-01T09:30:00Z\"}\n{\"id\":3,\"bookedAt\":\"2024-03-02T14:00:00Z\"}\n",
+		},
+		{
+			name:   "minimum tickets",
+			opts:   ExportOptions{Fields: []string{"id", "numberOfTickets"}, MinTickets: 2},
+			csv:    "id,numberOfTickets\n2,2\n3,4\n",
+			json:   "[\n  {\n    \"id\": 2,\n    \"numberOfTickets\": 2\n  },\n  {\n    \"id\": 3,\n    \"numberOfTickets\": 4\n  }\n]\n",
+			ndjson: "{\"id\":2,\"numberOfTickets\":2}\n{\"id\":3,\"numberOfTickets\":4}\n",
+		},
+		{
+			name:   "maximum tickets",
+			opts:   ExportOptions{Fields: []string{"id", "numberOfTickets"}, MaxTickets: 2},
+			csv:    "id,numberOfTickets\n1,1\n2,2\n",
+			json:   "[\n  {\n    \"id\": 1,\n    \"numberOfTickets\": 1\n  },\n  {\n    \"id\": 2,\n    \"numberOfTickets\": 2\n  }\n]\n",
+			ndjson: "{\"id\":1,\"numberOfTickets\":1}\n{\"id\":2,\"numberOfTickets\":2}\n",
+		},
+		{
+			name:   "filters combined",
+			opts:   ExportOptions{Fields: []string{"id"}, EmailDomain: "example.com", MinTickets: 2},
+			csv:    "id\n3\n",
+			json:   "[\n  {\n    \"id\": 3\n  }\n]\n",
+			ndjson: "{\"id\":3}\n",
+		},
+		{
+			name:   "nothing matches",
+			opts:   ExportOptions{Fields: []string{"id", "email"}, EmailDomain: "nowhere.net"},
+			csv:    "id,email\n",
+			json:   "[]\n",
+			ndjson: "",
+		},
+	}
+	for _, test := range tests {
+		for _, format := range []struct {
+			name   string
+			format ExportFormat
+			want   string
+		}{
+			{"csv", ExportCSV, test.csv},
+			{"json", ExportJSON, test.json},
+			{"ndjson", ExportNDJSON, test.ndjson},
+		} {
+			opts := test.opts
+			opts.Format = format.format
+			var buf bytes.Buffer
+			if err := Export(&buf, exportBookings(), opts); err != nil {
+				t.Errorf("%v as %v: %v", test.name, format.name, err)
+				continue
+			}
+			if got := buf.String(); got != format.want {
+				t.Errorf("%v as %v:\ngot:\n%v\nwant:\n%v", test.name, format.name, got, format.want)
+			}
+		}
+	}
+}
+
+func TestExportDefaultFields(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Export(&buf, exportBookings()[:1], ExportOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	want := strings.Join(ExportFields, ",") + "\n" +
+		"1,AAAA1111,Ada,Lovelace,ada@example.com,1,1 x general,5000,,0,5000,EUR,2024-03-01T09:30:00Z\n"
+	if got := buf.String(); got != want {
+		t.Errorf("got:\n%v\nwant:\n%v", got, want)
+	}
+}
+
+func TestExportUnknownField(t *testing.T) {
+	for _, format := range []ExportFormat{ExportCSV, ExportJSON, ExportNDJSON} {
+		var buf bytes.Buffer
+		err := Export(&buf, exportBookings(), ExportOptions{Format: format, Fields: []string{"id", "password"}})
+		if err == nil || !strings.Contains(err.Error(), `"password"`) {
+			t.Errorf("format %v: err = %v, want an unknown field error", format, err)
+		}
+		if buf.Len() > 0 {
+			t.Errorf("format %v: wrote %q before failing", format, buf.String())
+		}
+	}
+}
+
+func TestParseExportFormat(t *testing.T) {
+	tests := []struct {
+		s    string
+		want ExportFormat
+	}{
+		{"", ExportCSV},
+		{"csv", ExportCSV},
+		{"JSON", ExportJSON},
+		{"ndjson", ExportNDJSON},
+		{"jsonl", ExportNDJSON},
+	}
+	for _, test := range tests {
+		if got, err := ParseExportFormat(test.s); err != nil || got != test.want {
+			t.Errorf("ParseExportFormat(%q) = %v, %v, want %v", test.s, got, err, test.want)
+		}
+	}
+	if _, err := ParseExportFormat("xml"); err == nil {
+		t.Error("xml was accepted")
+	}
+}
@@ -0,0 +1,300 @@
+package booking
+
+import (
+	"errors"
+	"time"
+)
+
+// DefaultHoldTTL is how long a reservation holds its tickets unless asked otherwise
+const DefaultHoldTTL = 15 * time.Minute
+
+// Errors returned for reservations
+var (
+	ErrHoldNotFound = errors.New("booking: hold not found")
+	ErrHoldExpired  = errors.New("booking: hold has expired")
+	// ErrHoldOffered is returned for the hold of a waitlist offer, which only
+	// the person offered the tickets can accept or decline
+	ErrHoldOffered = errors.New("booking: hold belongs to a waitlist offer")
+)
+
+// Hold reserves tickets for a short time, for example while an attendee
+// goes through checkout. Held tickets do not count as remaining; they are
+// booked by Confirm or go back on sale when the hold is released or expires.
+type Hold struct {
+	ID              uint          `json:"id"`
+	Items           []TicketOrder `json:"items"`
+	NumberOfTickets uint          `json:"numberOfTickets"`
+	Expires         time.Time     `json:"expires"`
+	// WaitlistEntry is the ID of the waitlist entry the tickets are offered to, if any
+	WaitlistEntry uint `json:"waitlistEntry,omitempty"`
+}
+
+// Reserve holds the ordered tickets for ttl, or DefaultHoldTTL if ttl is not positive
+func (c *Conference) Reserve(items []TicketOrder, ttl time.Duration) (Hold, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.salesClosed {
+		return Hold{}, ErrSalesClosed
+	}
+	return c.reserveLocked(items, ttl, 0)
+}
+
+// Confirm turns a hold into a booking of the held tickets for the person
+// in order. The tickets in order.Items are ignored.
+func (c *Conference) Confirm(id uint, order Order) (UserData, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	i := c.findHold(id)
+	if i < 0 {
+		return UserData{}, ErrHoldNotFound
+	}
+	if c.holds[i].WaitlistEntry != 0 {
+		return UserData{}, ErrHoldOffered
+	}
+	if !time.Now().Before(c.holds[i].Expires) {
+		// The expiry goroutine has not got to it yet
+		c.dropHold(i)
+		c.offerFreedTickets()
+		return UserData{}, ErrHoldExpired
+	}
+	return c.confirmLocked(i, order)
+}
+
+// Release gives up a hold before it expires, putting its tickets back on sale.
+// The holds of waitlist offers are declined with LeaveWaitlist instead.
+func (c *Conference) Release(id uint) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	i := c.findHold(id)
+	if i < 0 {
+		return ErrHoldNotFound
+	}
+	if c.holds[i].WaitlistEntry != 0 {
+		return ErrHoldOffered
+	}
+	c.dropHold(i)
+	c.offerFreedTickets()
+	return nil
+}
+
+// Hold returns the current hold with the given ID. The holds of waitlist
+// offers are not handed out; they are reported as ErrHoldOffered.
+func (c *Conference) Hold(id uint) (Hold, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	i := c.findHold(id)
+	if i < 0 {
+		return Hold{}, ErrHoldNotFound
+	}
+	if c.holds[i].WaitlistEntry != 0 {
+		return Hold{}, ErrHoldOffered
+	}
+	return c.holds[i], nil
+}
+
+// Holds returns all current holds except those of waitlist offers, which
+// are listed with their entries by Waitlist
+func (c *Conference) Holds() []Hold {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	holds := make([]Hold, 0, len(c.holds))
+	for _, hold := range c.holds {
+		if hold.WaitlistEntry == 0 {
+			holds = append(holds, hold)
+		}
+	}
+	return holds
+}
+
+// HeldInTier returns the number of tickets of the tier that are currently held
+func (c *Conference) HeldInTier(tierID string) uint {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var held uint
+	for _, hold := range c.holds {
+		for _, item := range hold.Items {
+			if item.Tier == tierID {
+				held += item.Quantity
+			}
+		}
+	}
+	return held
+}
+
+// reserveLocked takes the ordered tickets off sale and records a hold for them.
+// Offers to a waitlist entry may take tickets kept for the waitlist.
+// Callers must hold c.mu.
+func (c *Conference) reserveLocked(items []TicketOrder, ttl time.Duration, waitlistEntry uint) (Hold, error) {
+	if ttl <= 0 {
+		ttl = DefaultHoldTTL
+	}
+
+	hold := Hold{ID: c.lastHoldID + 1, Expires: time.Now().Add(ttl), WaitlistEntry: waitlistEntry}
+	requested := make(map[string]uint)
+	for _, item := range items {
+		tier, err := c.Tier(item.Tier)
+		if err != nil {
+			return Hold{}, err
+		}
+		if item.Quantity == 0 {
+			continue
+		}
+		requested[tier.ID] += item.Quantity
+		available := c.remaining[tier.ID]
+		if waitlistEntry == 0 {
+			available = c.forSale(tier.ID)
+		}
+		if requested[tier.ID] > available {
+			return Hold{}, ErrNotEnoughTickets
+		}
+		hold.Items = append(hold.Items, TicketOrder{Tier: tier.ID, Quantity: item.Quantity})
+		hold.NumberOfTickets += item.Quantity
+	}
+	if hold.NumberOfTickets == 0 {
+		return Hold{}, ErrNoTickets
+	}
+
+	for tierID, quantity := range requested {
+		c.remaining[tierID] -= quantity
+	}
+	c.lastHoldID = hold.ID
+	c.holds = append(c.holds, hold)
+	c.watchHolds()
+	return hold, nil
+}
+
+// confirmLocked books the tickets of hold i. If booking fails the hold stays.
+// Callers must hold c.mu.
+func (c *Conference) confirmLocked(i int, order Order) (UserData, error) {
+	hold := c.holds[i]
+
+	// Hand the held tickets back so the booking can take them
+	for _, item := range hold.Items {
+		c.remaining[item.Tier] += item.Quantity
+	}
+	order.Items = hold.Items
+	userData, err := c.bookLocked(order, true)
+	if err != nil {
+		for _, item := range hold.Items {
+			c.remaining[item.Tier] -= item.Quantity
+		}
+		return UserData{}, err
+	}
+
+	c.holds = append(c.holds[:i], c.holds[i+1:]...)
+	return userData, nil
+}
+
+// releaseHold puts the tickets of hold i back on sale and forgets the hold.
+// Callers must hold c.mu.
+func (c *Conference) releaseHold(i int) {
+	for _, item := range c.holds[i].Items {
+		c.remaining[item.Tier] += item.Quantity
+	}
+	c.holds = append(c.holds[:i], c.holds[i+1:]...)
+}
+
+// dropHold releases hold i together with the waitlist entry it was offered to.
+// Callers must hold c.mu.
+func (c *Conference) dropHold(i int) {
+	waitlistEntry := c.holds[i].WaitlistEntry
+	c.releaseHold(i)
+	if waitlistEntry == 0 {
+		return
+	}
+	if j := c.findWaitlistEntry(waitlistEntry); j >= 0 {
+		c.waitlist = append(c.waitlist[:j], c.waitlist[j+1:]...)
+		c.saveWaitlist()
+	}
+}
+
+// findHold returns the index of the hold with the given ID, or -1.
+// Callers must hold c.mu.
+func (c *Conference) findHold(id uint) int {
+	for i, hold := range c.holds {
+		if hold.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// watchHolds makes sure the expiry goroutine knows about a new hold,
+// starting it if no hold was being watched.
+// Callers must hold c.mu.
+func (c *Conference) watchHolds() {
+	if c.expiringHolds {
+		select {
+		case c.holdsChanged <- struct{}{}:
+		default:
+			// The goroutine already has a wake-up pending
+		}
+		return
+	}
+	c.expiringHolds = true
+	go c.expireHolds()
+}
+
+// expireHolds runs in the background while there are holds, releasing each
+// one when it expires and offering the freed tickets to the waitlist.
+// It sleeps until the next hold expires or a new hold is added.
+func (c *Conference) expireHolds() {
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-timer.C:
+		case <-c.holdsChanged:
+		}
+
+		c.mu.Lock()
+		next := c.releaseExpiredHolds(time.Now())
+		if next.IsZero() {
+			c.expiringHolds = false
+			c.mu.Unlock()
+			return
+		}
+		c.mu.Unlock()
+
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		timer.Reset(time.Until(next))
+	}
+}
+
+// releaseExpiredHolds drops every hold that expired by now and returns when
+// the next remaining hold expires, or the zero time if none is left.
+// Callers must hold c.mu.
+func (c *Conference) releaseExpiredHolds(now time.Time) time.Time {
+	released := false
+	for i := 0; i < len(c.holds); {
+		if now.Before(c.holds[i].Expires) {
+			i++
+			continue
+		}
+		c.dropHold(i)
+		released = true
+	}
+	if released {
+		c.offerFreedTickets()
+	}
+
+	var next time.Time
+	for _, hold := range c.holds {
+		if next.IsZero() || hold.Expires.Before(next) {
+			next = hold.Expires
+		}
+	}
+	return next
+}
@@ -0,0 +1,115 @@
+package booking
+
+import (
+	"errors"
+	"strings"
+)
+
+// ErrTicketLimit is returned when a booking would give one person more
+// tickets than the TicketLimits of the conference allow
+var ErrTicketLimit = errors.New("booking: ticket limit per person reached")
+
+// TicketLimits caps how many tickets one person may book for a conference.
+// Zero means no cap.
+type TicketLimits struct {
+	// PerEmail counts the bookings of all spellings of an address (see NormalizeEmail)
+	PerEmail uint
+	// PerName counts the bookings with the same first and last name, ignoring case
+	PerName uint
+}
+
+// SetTicketLimits caps the tickets one person may book from now on.
+// Bookings already made are not affected.
+func (c *Conference) SetTicketLimits(limits TicketLimits) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.limits = limits
+}
+
+// TicketLimits returns the caps set with SetTicketLimits
+func (c *Conference) TicketLimits() TicketLimits {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.limits
+}
+
+// TicketsBookedBy returns the number of tickets booked with the email address
+// or any other spelling of it, such as a different case or "+tag"
+func (c *Conference) TicketsBookedBy(email string) (uint, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	bookings, err := c.store.List()
+	if err != nil {
+		return 0, err
+	}
+	return ticketsBookedBy(bookings, email), nil
+}
+
+// TicketsBookedByName returns the number of tickets booked under the name,
+// compared in normalized form and ignoring case
+func (c *Conference) TicketsBookedByName(firstName string, lastName string) (uint, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	bookings, err := c.store.List()
+	if err != nil {
+		return 0, err
+	}
+	return ticketsBookedByName(bookings, firstName, lastName), nil
+}
+
+// checkTicketLimits returns ErrTicketLimit if booking userData would exceed
+// a ticket limit. It runs under the same lock as the booking, so concurrent
+// bookings by one person cannot get past the limits together. With
+// countWaitlist the tickets the person waits for count as booked too.
+// Callers must hold c.mu.
+func (c *Conference) checkTicketLimits(userData UserData, countWaitlist bool) error {
+	if c.limits.PerEmail == 0 && c.limits.PerName == 0 {
+		return nil
+	}
+	bookings, err := c.store.List()
+	if err != nil {
+		return err
+	}
+	if countWaitlist {
+		for _, entry := range c.waitlist {
+			bookings = append(bookings, UserData{FirstName: entry.FirstName, LastName: entry.LastName, Email: entry.Email, NumberOfTickets: entry.NumberOfTickets})
+		}
+	}
+	if c.limits.PerEmail > 0 && ticketsBookedBy(bookings, userData.Email)+userData.NumberOfTickets > c.limits.PerEmail {
+		return ErrTicketLimit
+	}
+	if c.limits.PerName > 0 && ticketsBookedByName(bookings, userData.FirstName, userData.LastName)+userData.NumberOfTickets > c.limits.PerName {
+		return ErrTicketLimit
+	}
+	return nil
+}
+
+// ticketsBookedBy counts the tickets of bookings made with any spelling of email
+func ticketsBookedBy(bookings []UserData, email string) uint {
+	email = NormalizeEmail(email)
+	var tickets uint
+	for _, booking := range bookings {
+		if NormalizeEmail(booking.Email) == email {
+			tickets += booking.NumberOfTickets
+		}
+	}
+	return tickets
+}
+
+// ticketsBookedByName counts the tickets of bookings made under the name.
+// Stored names are normalized again, since bookings made before names were
+// composed may still hold combining marks.
+func ticketsBookedByName(bookings []UserData, firstName string, lastName string) uint {
+	firstName, lastName = NormalizeName(firstName), NormalizeName(lastName)
+	var tickets uint
+	for _, booking := range bookings {
+		if strings.EqualFold(NormalizeName(booking.FirstName), firstName) && strings.EqualFold(NormalizeName(booking.LastName), lastName) {
+			tickets += booking.NumberOfTickets
+		}
+	}
+	return tickets
+}
@@ -0,0 +1,58 @@
+package booking
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func TestConcurrentBookingsKeepTicketLimits(t *testing.T) {
+	conference := NewConference(Event{ID: "limits", Name: "Limits", Tickets: 1000})
+	conference.SetTicketLimits(TicketLimits{PerEmail: 4, PerName: 6})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 200; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			// Spellings of two addresses, all under the same name
+			email := fmt.Sprintf("Ada+%v@Example.com", i)
+			if i%2 == 1 {
+				email = fmt.Sprintf("grace+%v@example.com", i)
+			}
+			_, err := conference.Book(1, "Ada", "Lovelace", email)
+			if err != nil && !errors.Is(err, ErrTicketLimit) {
+				t.Errorf("Book: %v", err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	byEmail, err := conference.TicketsBookedBy("ada@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	byName, err := conference.TicketsBookedByName("ada", "LOVELACE")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if byEmail > 4 {
+		t.Errorf("%v tickets booked for ada@example.com, limit 4", byEmail)
+	}
+	if byName != 6 {
+		t.Errorf("%v tickets booked for Ada Lovelace, want the limit of 6", byName)
+	}
+}
+
+func TestTicketLimitPerNameIgnoresCombiningMarks(t *testing.T) {
+	conference := NewConference(Event{ID: "marks", Name: "Marks", Tickets: 10})
+	conference.SetTicketLimits(TicketLimits{PerName: 2})
+
+	if _, err := conference.Book(2, "Zo\u00EB", "Smith", "zoe@example.com"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := conference.Book(1, "Zoe\u0308", "Smith", "zoe.smith@example.com"); !errors.Is(err, ErrTicketLimit) {
+		t.Errorf("booking with a decomposed name = %v, want ErrTicketLimit", err)
+	}
+}
@@ -0,0 +1,125 @@
+package booking
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// NameRules configures ValidateName.
+// Lengths are counted in user-perceived characters, not bytes, so "Zoë" and
+// "李" count as three and one characters in every encoding of them.
+type NameRules struct {
+	MinLength int
+	MaxLength int
+	// Punctuation lists the non-letter characters allowed between letters,
+	// e.g. the space in "Mary Ann" or the apostrophe in "O'Brien"
+	Punctuation string
+}
+
+// DefaultNameRules are the name rules used unless configured otherwise
+var DefaultNameRules = NameRules{
+	MinLength:   2,
+	MaxLength:   50,
+	Punctuation: " -'.",
+}
+
+// NameError is returned by ValidateName; Code is one of the Code* constants
+type NameError struct {
+	Code    string
+	Message string
+}
+
+func (e *NameError) Error() string {
+	return e.Message
+}
+
+// NormalizeName trims the name and collapses runs of white space into a
+// single space. The letters are kept as typed: "van der Berg" and "McDonald"
+// are spelled the way their owners spell them. Latin letters with combining
+// marks are composed (see composeLatin), so "Zoë" is stored the same way
+// whether it was typed with a precomposed "ë" or "e" and a diaeresis.
+func NormalizeName(name string) string {
+	return composeLatin(strings.Join(strings.Fields(name), " "))
+}
+
+// ValidateName checks the normalized name against the rules.
+// Letters from any script are allowed; digits, symbols and control
+// characters are rejected, as is punctuation not listed in the rules.
+// Punctuation may only separate letters, so a name must start with a letter
+// and may not repeat punctuation; only a period may follow a letter at the
+// end or before a space, as in "Jr." or "St. John". MinLength counts the
+// letters alone, MaxLength the whole name.
+func ValidateName(name string, rules NameRules) error {
+	name = NormalizeName(name)
+
+	var previous rune
+	for i, r := range name {
+		if unicode.IsLetter(r) || unicode.IsMark(r) || isGraphemeJoiner(r) {
+			if i == 0 && !unicode.IsLetter(r) {
+				return &NameError{Code: CodeInvalidPunctuation, Message: "must start with a letter"}
+			}
+			previous = r
+			continue
+		}
+		if !strings.ContainsRune(rules.Punctuation, r) {
+			return &NameError{Code: CodeInvalidCharacters, Message: fmt.Sprintf("contains the invalid character %q", r)}
+		}
+		switch {
+		case i == 0:
+			return &NameError{Code: CodeInvalidPunctuation, Message: "must start with a letter"}
+		case isNamePunctuation(previous, rules) && !(previous == '.' && r == ' '):
+			return &NameError{Code: CodeInvalidPunctuation, Message: fmt.Sprintf("contains %q right after %q", r, previous)}
+		}
+		previous = r
+	}
+	if isNamePunctuation(previous, rules) && previous != '.' {
+		return &NameError{Code: CodeInvalidPunctuation, Message: fmt.Sprintf("ends with %q", previous)}
+	}
+
+	length, letters := nameLength(name)
+	minLetters := rules.MinLength
+	if minLetters < 1 {
+		minLetters = 1
+	}
+	if letters < minLetters {
+		return &NameError{Code: CodeTooShort, Message: fmt.Sprintf("is too short (min %v letters)", minLetters)}
+	}
+	if rules.MaxLength > 0 && length > rules.MaxLength {
+		return &NameError{Code: CodeTooLong, Message: fmt.Sprintf("is too long (max %v characters)", rules.MaxLength)}
+	}
+	return nil
+}
+
+// isNamePunctuation reports whether r is one of the punctuation characters of the rules
+func isNamePunctuation(r rune, rules NameRules) bool {
+	return r != 0 && strings.ContainsRune(rules.Punctuation, r)
+}
+
+// nameLength approximates the number of grapheme clusters in name, and
+// how many of them are letters: combining marks and the characters that
+// join others into one visible character do not count on their own.
+func nameLength(name string) (length int, letters int) {
+	joinNext := false
+	for _, r := range name {
+		switch {
+		case unicode.IsMark(r) || (r >= 0xFE00 && r <= 0xFE0F):
+			// Combining marks and variation selectors extend the previous character
+		case r == '\u200d':
+			joinNext = true
+		case joinNext:
+			joinNext = false
+		default:
+			length++
+			if unicode.IsLetter(r) {
+				letters++
+			}
+		}
+	}
+	return length, letters
+}
+
+// isGraphemeJoiner reports whether r is the zero width joiner or a variation selector
+func isGraphemeJoiner(r rune) bool {
+	return r == '\u200d' || (r >= 0xFE00 && r <= 0xFE0F)
+}
@@ -0,0 +1,63 @@
+package booking
+
+import "testing"
+
+func TestValidateName(t *testing.T) {
+	tests := []struct {
+		name string
+		code string // empty if the name is valid
+	}{
+		{"Mary Ann", ""},
+		{"O'Brien", ""},
+		{"Anne-Marie", ""},
+		{"van der Berg", ""},
+		{"St. John", ""},
+		{"Jr.", ""},
+		{"Zoë", ""},
+		{"李白", ""},
+		{"--", CodeInvalidPunctuation},
+		{"''", CodeInvalidPunctuation},
+		{". .", CodeInvalidPunctuation},
+		{"-Ann", CodeInvalidPunctuation},
+		{"Ann-", CodeInvalidPunctuation},
+		{"Ann--Marie", CodeInvalidPunctuation},
+		{"O''Brien", CodeInvalidPunctuation},
+		{"A", CodeTooShort},
+		{"A.", CodeTooShort},
+		{"R2D2", CodeInvalidCharacters},
+	}
+	for _, test := range tests {
+		err := ValidateName(test.name, DefaultNameRules)
+		code := ""
+		if nameErr, ok := err.(*NameError); ok {
+			code = nameErr.Code
+		} else if err != nil {
+			t.Fatalf("ValidateName(%q) returned %T, want *NameError", test.name, err)
+		}
+		if code != test.code {
+			t.Errorf("ValidateName(%q) = %v, want code %q", test.name, err, test.code)
+		}
+	}
+}
+
+func TestNormalizeNameKeepsCase(t *testing.T) {
+	if got := NormalizeName("  van der   Berg "); got != "van der Berg" {
+		t.Errorf("NormalizeName = %q, want %q", got, "van der Berg")
+	}
+}
+
+func TestNormalizeNameComposesLatinMarks(t *testing.T) {
+	tests := map[string]string{
+		"Zoe\u0308":            "Zo\u00EB",
+		"Zo\u00EB":             "Zo\u00EB",
+		"Franc\u0327ois":       "Fran\u00E7ois",
+		"Nguye\u0323\u0302n":   "Nguy\u1EC7n",
+		"A\u030Angstro\u0308m": "\u00C5ngstr\u00F6m",
+		"\u674E\u767D":         "\u674E\u767D",
+	}
+	for name, want := range tests {
+		if got := NormalizeName(name); got != want {
+			t.Errorf("NormalizeName(%+q) = %+q, want %+q", name, got, want)
+		}
+	}
+}
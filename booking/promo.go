@@ -0,0 +1,141 @@
+package booking
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+// Errors returned when a promo code cannot be applied to a booking
+var (
+	ErrPromoUnknown       = errors.New("booking: unknown promo code")
+	ErrPromoExpired       = errors.New("booking: promo code has expired")
+	ErrPromoUsedUp        = errors.New("booking: promo code has been used up")
+	ErrPromoEmailLimit    = errors.New("booking: promo code already redeemed by this email address")
+	ErrPromoNotApplicable = errors.New("booking: promo code does not apply to the ordered tickets")
+)
+
+// PromoCode gives a discount on bookings of an event.
+// Exactly one of Percent and Amount should be set.
+type PromoCode struct {
+	Code string `json:"code"`
+	// Percent is a discount in percent (1-100) of the applicable tickets
+	Percent int64 `json:"percent,omitempty"`
+	// Amount is a fixed discount per booking in minor units, never more than the applicable tickets cost
+	Amount int64 `json:"amount,omitempty"`
+	// MaxUses limits how many bookings may use the code; 0 means unlimited
+	MaxUses int `json:"maxUses,omitempty"`
+	// MaxUsesPerEmail limits how many bookings one email address may use the code for; 0 means unlimited
+	MaxUsesPerEmail int `json:"maxUsesPerEmail,omitempty"`
+	// Expires is the moment the code stops working; the zero time never expires
+	Expires time.Time `json:"expires,omitempty"`
+	// Tiers lists the tier IDs the discount applies to; empty means all tiers
+	Tiers []string `json:"tiers,omitempty"`
+}
+
+// appliesTo reports whether the code gives a discount on tickets of the tier
+func (p PromoCode) appliesTo(tierID string) bool {
+	if len(p.Tiers) == 0 {
+		return true
+	}
+	for _, id := range p.Tiers {
+		if id == tierID {
+			return true
+		}
+	}
+	return false
+}
+
+// discount returns the discount the code gives on the line items
+func (p PromoCode) discount(items []LineItem) int64 {
+	var applicable int64
+	for _, item := range items {
+		if p.appliesTo(item.Tier) {
+			applicable += item.Total
+		}
+	}
+
+	if p.Percent > 0 {
+		return applicable * p.Percent / 100
+	}
+	if p.Amount > applicable {
+		return applicable
+	}
+	return p.Amount
+}
+
+// promoUsage counts redemptions of one promo code, in total and per email address
+type promoUsage struct {
+	total   int
+	byEmail map[string]int
+}
+
+// findPromoCode looks up a promo code of the event, ignoring case
+func (e Event) findPromoCode(code string) (PromoCode, bool) {
+	for _, promo := range e.PromoCodes {
+		if strings.EqualFold(promo.Code, code) {
+			return promo, true
+		}
+	}
+	return PromoCode{}, false
+}
+
+// applyPromoCode checks that the code can be used by email for the line items
+// and returns it with the discount it gives. Callers must hold c.mu.
+func (c *Conference) applyPromoCode(code string, email string, items []LineItem, now time.Time) (PromoCode, int64, error) {
+	promo, ok := c.event.findPromoCode(code)
+	if !ok {
+		return PromoCode{}, 0, ErrPromoUnknown
+	}
+	if !promo.Expires.IsZero() && !now.Before(promo.Expires) {
+		return PromoCode{}, 0, ErrPromoExpired
+	}
+
+	if usage, ok := c.promoUsage[promo.Code]; ok {
+		if promo.MaxUses > 0 && usage.total >= promo.MaxUses {
+			return PromoCode{}, 0, ErrPromoUsedUp
+		}
+		if promo.MaxUsesPerEmail > 0 && usage.byEmail[NormalizeEmail(email)] >= promo.MaxUsesPerEmail {
+			return PromoCode{}, 0, ErrPromoEmailLimit
+		}
+	}
+
+	discount := promo.discount(items)
+	if discount == 0 {
+		return PromoCode{}, 0, ErrPromoNotApplicable
+	}
+	return promo, discount, nil
+}
+
+// CheckPromoCode reports whether the code could be applied to a booking of
+// the given tickets by email right now, without redeeming it
+func (c *Conference) CheckPromoCode(code string, email string, orders []TicketOrder) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var items []LineItem
+	for _, order := range orders {
+		tier, err := c.Tier(order.Tier)
+		if err != nil {
+			return err
+		}
+		items = append(items, LineItem{Tier: tier.ID, Quantity: order.Quantity, UnitPrice: tier.Price, Total: tier.Price * int64(order.Quantity)})
+	}
+
+	_, _, err := c.applyPromoCode(code, email, items, time.Now())
+	return err
+}
+
+// countPromoCode records (delta 1) or releases (delta -1) a redemption. Callers must hold c.mu.
+func (c *Conference) countPromoCode(code string, email string, delta int) {
+	if code == "" {
+		return
+	}
+	usage, ok := c.promoUsage[code]
+	if !ok {
+		usage = &promoUsage{byEmail: make(map[string]int)}
+		c.promoUsage[code] = usage
+	}
+	usage.total += delta
+	usage.byEmail[NormalizeEmail(email)] += delta
+}
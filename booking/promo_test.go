@@ -0,0 +1,118 @@
+package booking
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// promoEvent sells general tickets at 100.00 and student tickets at 40.00 EUR
+func promoEvent(promos ...PromoCode) Event {
+	return Event{ID: "promo", Name: "Promo", Currency: "EUR",
+		Tiers: []Tier{
+			{ID: "general", Name: "General", Price: 10000, Tickets: 100},
+			{ID: "student", Name: "Student", Price: 4000, Tickets: 100},
+		},
+		PromoCodes: promos}
+}
+
+func TestPromoCodeDiscount(t *testing.T) {
+	items := []LineItem{
+		{Tier: "general", Quantity: 2, UnitPrice: 10000, Total: 20000},
+		{Tier: "student", Quantity: 1, UnitPrice: 4000, Total: 4000},
+	}
+	tests := []struct {
+		name  string
+		promo PromoCode
+		want  int64
+	}{
+		{"percent of everything", PromoCode{Percent: 10}, 2400},
+		{"percent rounds down", PromoCode{Percent: 33}, 7920},
+		{"full price", PromoCode{Percent: 100}, 24000},
+		{"percent of one tier", PromoCode{Percent: 50, Tiers: []string{"student"}}, 2000},
+		{"fixed amount", PromoCode{Amount: 1500}, 1500},
+		{"fixed amount capped at the applicable tickets", PromoCode{Amount: 5000, Tiers: []string{"student"}}, 4000},
+		{"tier not ordered", PromoCode{Percent: 10, Tiers: []string{"vip"}}, 0},
+	}
+	for _, test := range tests {
+		if got := test.promo.discount(items); got != test.want {
+			t.Errorf("%v: discount = %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestBookOrderAppliesPromoCode(t *testing.T) {
+	conference := NewConference(promoEvent(PromoCode{Code: "STUDENT50", Percent: 50, Tiers: []string{"student"}}))
+	booked, err := conference.BookOrder(Order{FirstName: "Ada", LastName: "Lovelace", Email: "ada@example.com",
+		Items: []TicketOrder{{Tier: "general", Quantity: 1}, {Tier: "student", Quantity: 2}}, PromoCode: "student50"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if booked.PromoCode != "STUDENT50" || booked.Subtotal != 18000 || booked.Discount != 4000 || booked.Total != 14000 {
+		t.Errorf("booking has code %q, subtotal %v, discount %v, total %v; want STUDENT50, 18000, 4000, 14000",
+			booked.PromoCode, booked.Subtotal, booked.Discount, booked.Total)
+	}
+
+	_, err = conference.BookOrder(Order{FirstName: "Ada", LastName: "Lovelace", Email: "ada@example.com",
+		Items: []TicketOrder{{Tier: "general", Quantity: 1}}, PromoCode: "STUDENT50"})
+	if !errors.Is(err, ErrPromoNotApplicable) {
+		t.Errorf("code on tickets it does not apply to = %v, want ErrPromoNotApplicable", err)
+	}
+}
+
+func TestPromoCodeExpiry(t *testing.T) {
+	now := time.Now()
+	conference := NewConference(promoEvent(
+		PromoCode{Code: "PAST", Percent: 10, Expires: now.Add(-time.Minute)},
+		PromoCode{Code: "FUTURE", Percent: 10, Expires: now.Add(time.Hour)},
+	))
+	orders := []TicketOrder{{Tier: "general", Quantity: 1}}
+	if err := conference.CheckPromoCode("PAST", "ada@example.com", orders); !errors.Is(err, ErrPromoExpired) {
+		t.Errorf("expired code = %v, want ErrPromoExpired", err)
+	}
+	if err := conference.CheckPromoCode("FUTURE", "ada@example.com", orders); err != nil {
+		t.Errorf("code that expires later = %v, want nil", err)
+	}
+	if err := conference.CheckPromoCode("NOSUCH", "ada@example.com", orders); !errors.Is(err, ErrPromoUnknown) {
+		t.Errorf("unknown code = %v, want ErrPromoUnknown", err)
+	}
+}
+
+func TestPromoCodeUsageLimits(t *testing.T) {
+	conference := NewConference(promoEvent(PromoCode{Code: "EARLY", Amount: 1000, MaxUses: 2, MaxUsesPerEmail: 1}))
+	book := func(email string) (UserData, error) {
+		return conference.BookOrder(Order{FirstName: "Ada", LastName: "Lovelace", Email: email,
+			Items: []TicketOrder{{Tier: "general", Quantity: 2}}, PromoCode: "EARLY"})
+	}
+
+	first, err := book("ada@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := book("Ada+again@Example.com"); !errors.Is(err, ErrPromoEmailLimit) {
+		t.Errorf("second use by the same address = %v, want ErrPromoEmailLimit", err)
+	}
+	if _, err := book("grace@example.com"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := book("alan@example.com"); !errors.Is(err, ErrPromoUsedUp) {
+		t.Errorf("third use = %v, want ErrPromoUsedUp", err)
+	}
+
+	// A partial cancellation keeps the redemption, a full one releases it
+	if _, err := conference.CancelTickets(first.ID, []TicketOrder{{Tier: "general", Quantity: 1}}, ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := book("alan@example.com"); !errors.Is(err, ErrPromoUsedUp) {
+		t.Errorf("use after a partial cancellation = %v, want ErrPromoUsedUp", err)
+	}
+	if _, err := conference.Cancel(first.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := book("alan@example.com"); err != nil {
+		t.Errorf("use after a full cancellation = %v, want nil", err)
+	}
+	if _, err := book("ada@example.com"); !errors.Is(err, ErrPromoUsedUp) {
+		t.Errorf("use beyond MaxUses again = %v, want ErrPromoUsedUp", err)
+	}
+}
@@ -0,0 +1,114 @@
+package booking
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"sync"
+)
+
+// ErrConferenceNotFound is returned when no conference has the requested ID
+var ErrConferenceNotFound = errors.New("booking: conference not found")
+
+// Registry holds all conferences managed by one application
+type Registry struct {
+	mu          sync.RWMutex
+	conferences map[string]*Conference
+}
+
+// NewRegistry creates an empty registry
+func NewRegistry() *Registry {
+	return &Registry{conferences: make(map[string]*Conference)}
+}
+
+// Add registers a conference; IDs must be unique
+func (r *Registry) Add(conference *Conference) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.conferences[conference.ID()]; ok {
+		return fmt.Errorf("booking: conference %q is already registered", conference.ID())
+	}
+	r.conferences[conference.ID()] = conference
+	return nil
+}
+
+// Get returns the conference with the given ID
+func (r *Registry) Get(id string) (*Conference, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	conference, ok := r.conferences[id]
+	if !ok {
+		return nil, ErrConferenceNotFound
+	}
+	return conference, nil
+}
+
+// List returns all conferences ordered by start date, then by ID
+func (r *Registry) List() []*Conference {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	conferences := make([]*Conference, 0, len(r.conferences))
+	for _, conference := range r.conferences {
+		conferences = append(conferences, conference)
+	}
+	sort.Slice(conferences, func(i, j int) bool {
+		a, b := conferences[i].Event(), conferences[j].Event()
+		if !a.Starts.Equal(b.Starts) {
+			return a.Starts.Before(b.Starts)
+		}
+		return a.ID < b.ID
+	})
+	return conferences
+}
+
+// CloseSales stops ticket sales for every conference
+func (r *Registry) CloseSales() {
+	for _, conference := range r.List() {
+		conference.CloseSales()
+	}
+}
+
+// LoadEvents reads a JSON array of events from the file at path
+func LoadEvents(path string) ([]Event, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var events []Event
+	if err := json.Unmarshal(data, &events); err != nil {
+		return nil, fmt.Errorf("booking: reading events from %v: %w", path, err)
+	}
+	for _, event := range events {
+		if event.ID == "" || event.Name == "" {
+			return nil, fmt.Errorf("booking: every event in %v needs an id and a name", path)
+		}
+		if !event.Ends.IsZero() && event.Ends.Before(event.Starts) {
+			return nil, fmt.Errorf("booking: event %q ends before it starts", event.ID)
+		}
+		tierIDs := make(map[string]bool)
+		for _, tier := range event.Tiers {
+			if tier.ID == "" || tierIDs[tier.ID] {
+				return nil, fmt.Errorf("booking: event %q has a tier without a unique id", event.ID)
+			}
+			if tier.Price < 0 {
+				return nil, fmt.Errorf("booking: tier %q of event %q has a negative price", tier.ID, event.ID)
+			}
+			tierIDs[tier.ID] = true
+		}
+		for _, promo := range event.PromoCodes {
+			if promo.Code == "" || (promo.Percent > 0) == (promo.Amount > 0) || promo.Percent > 100 {
+				return nil, fmt.Errorf("booking: promo codes of event %q need a code and either a percent (1-100) or an amount", event.ID)
+			}
+		}
+		if event.Refunds != nil && (event.Refunds.FeePercent < 0 || event.Refunds.FeePercent > 100) {
+			return nil, fmt.Errorf("booking: refund fee of event %q must be a percent from 0 to 100", event.ID)
+		}
+	}
+	return events, nil
+}
@@ -0,0 +1,244 @@
+package booking
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultStatsTop is the number of email domains and orders ranked by Stats
+// unless asked otherwise
+const DefaultStatsTop = 5
+
+// StatsOptions configures Stats. The zero value ranks DefaultStatsTop
+// entries and groups bookings by hour and day in local time.
+type StatsOptions struct {
+	Top      int
+	Location *time.Location
+}
+
+// Stats summarizes the ticket sales of a conference.
+// Amounts are in minor units of the event currency.
+type Stats struct {
+	ConferenceID     string  `json:"conferenceId"`
+	Name             string  `json:"name"`
+	Tickets          uint    `json:"tickets"`
+	TicketsSold      uint    `json:"ticketsSold"`
+	TicketsHeld      uint    `json:"ticketsHeld"`
+	TicketsRemaining uint    `json:"ticketsRemaining"`
+	PercentSold      float64 `json:"percentSold"`
+	Bookings         int     `json:"bookings"`
+	// AverageTickets is the mean number of tickets per booking
+	AverageTickets float64 `json:"averageTickets"`
+	Revenue        int64   `json:"revenue"`
+	Currency       string  `json:"currency,omitempty"`
+
+	TopEmailDomains []DomainCount `json:"topEmailDomains"`
+	// BookingsPerDay and BookingsPerHour only list periods with bookings, oldest first
+	BookingsPerDay  []PeriodCount `json:"bookingsPerDay"`
+	BookingsPerHour []PeriodCount `json:"bookingsPerHour"`
+	// UndatedBookings were stored before booking times were recorded and
+	// are left out of the periods
+	UndatedBookings int          `json:"undatedBookings,omitempty"`
+	LargestOrders   []OrderStats `json:"largestOrders"`
+}
+
+// DomainCount is the number of bookings and tickets for one email domain
+type DomainCount struct {
+	Domain   string `json:"domain"`
+	Bookings int    `json:"bookings"`
+	Tickets  uint   `json:"tickets"`
+}
+
+// PeriodCount is the number of bookings and tickets booked in the day or
+// hour starting at Start
+type PeriodCount struct {
+	Start    time.Time `json:"start"`
+	Bookings int       `json:"bookings"`
+	Tickets  uint      `json:"tickets"`
+}
+
+// OrderStats describes one booking in the ranking of the largest orders
+type OrderStats struct {
+	ID        uint      `json:"id"`
+	FirstName string    `json:"firstName"`
+	LastName  string    `json:"lastName"`
+	Email     string    `json:"email"`
+	Tickets   uint      `json:"tickets"`
+	Total     int64     `json:"total"`
+	BookedAt  time.Time `json:"bookedAt"`
+}
+
+// Stats summarizes the sales of the conference. The bookings, remaining
+// and held tickets are read together, so the numbers always add up.
+func (c *Conference) Stats(opts StatsOptions) (Stats, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	bookings, err := c.store.List()
+	if err != nil {
+		return Stats{}, err
+	}
+
+	stats := ComputeStats(c.event, bookings, opts)
+	for _, hold := range c.holds {
+		stats.TicketsHeld += hold.NumberOfTickets
+	}
+	stats.TicketsRemaining = 0
+	for _, remaining := range c.remaining {
+		stats.TicketsRemaining += remaining
+	}
+	return stats, nil
+}
+
+// ComputeStats summarizes the bookings of the event. Held and remaining
+// tickets are not known from the bookings alone; remaining is filled in as
+// the tickets that were not sold.
+func ComputeStats(event Event, bookings []UserData, opts StatsOptions) Stats {
+	if opts.Top <= 0 {
+		opts.Top = DefaultStatsTop
+	}
+	if opts.Location == nil {
+		opts.Location = time.Local
+	}
+
+	stats := Stats{
+		ConferenceID: event.ID,
+		Name:         event.Name,
+		Tickets:      event.Tickets,
+		Bookings:     len(bookings),
+		Currency:     event.Currency,
+	}
+
+	domains := make(map[string]*DomainCount)
+	days := make(map[time.Time]*PeriodCount)
+	hours := make(map[time.Time]*PeriodCount)
+	for _, booking := range bookings {
+		stats.TicketsSold += booking.NumberOfTickets
+		stats.Revenue += booking.Total
+
+		domain := emailDomain(booking.Email)
+		if domains[domain] == nil {
+			domains[domain] = &DomainCount{Domain: domain}
+		}
+		domains[domain].Bookings++
+		domains[domain].Tickets += booking.NumberOfTickets
+
+		if booking.BookedAt.IsZero() {
+			stats.UndatedBookings++
+			continue
+		}
+		bookedAt := booking.BookedAt.In(opts.Location)
+		day := time.Date(bookedAt.Year(), bookedAt.Month(), bookedAt.Day(), 0, 0, 0, 0, opts.Location)
+		hour := time.Date(bookedAt.Year(), bookedAt.Month(), bookedAt.Day(), bookedAt.Hour(), 0, 0, 0, opts.Location)
+		countPeriod(days, day, booking)
+		countPeriod(hours, hour, booking)
+	}
+
+	if stats.Tickets >= stats.TicketsSold {
+		stats.TicketsRemaining = stats.Tickets - stats.TicketsSold
+	}
+	if stats.Tickets > 0 {
+		stats.PercentSold = round2(float64(stats.TicketsSold) * 100 / float64(stats.Tickets))
+	}
+	if stats.Bookings > 0 {
+		stats.AverageTickets = round2(float64(stats.TicketsSold) / float64(stats.Bookings))
+	}
+
+	stats.TopEmailDomains = topDomains(domains, opts.Top)
+	stats.BookingsPerDay = sortedPeriods(days)
+	stats.BookingsPerHour = sortedPeriods(hours)
+	stats.LargestOrders = largestOrders(bookings, opts.Top)
+	return stats
+}
+
+// emailDomain returns the lower-case domain of an email address
+func emailDomain(email string) string {
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return ""
+	}
+	return strings.ToLower(email[at+1:])
+}
+
+// countPeriod adds the booking to the period starting at start
+func countPeriod(periods map[time.Time]*PeriodCount, start time.Time, booking UserData) {
+	if periods[start] == nil {
+		periods[start] = &PeriodCount{Start: start}
+	}
+	periods[start].Bookings++
+	periods[start].Tickets += booking.NumberOfTickets
+}
+
+// sortedPeriods returns the periods oldest first
+func sortedPeriods(periods map[time.Time]*PeriodCount) []PeriodCount {
+	sorted := make([]PeriodCount, 0, len(periods))
+	for _, period := range periods {
+		sorted = append(sorted, *period)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Start.Before(sorted[j].Start)
+	})
+	return sorted
+}
+
+// topDomains returns the top domains by tickets, then bookings, then name
+func topDomains(domains map[string]*DomainCount, top int) []DomainCount {
+	sorted := make([]DomainCount, 0, len(domains))
+	for _, domain := range domains {
+		sorted = append(sorted, *domain)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		a, b := sorted[i], sorted[j]
+		if a.Tickets != b.Tickets {
+			return a.Tickets > b.Tickets
+		}
+		if a.Bookings != b.Bookings {
+			return a.Bookings > b.Bookings
+		}
+		return a.Domain < b.Domain
+	})
+	if len(sorted) > top {
+		sorted = sorted[:top]
+	}
+	return sorted
+}
+
+// largestOrders returns the top bookings by tickets, then total; earlier
+// bookings win ties
+func largestOrders(bookings []UserData, top int) []OrderStats {
+	sorted := copyBookings(bookings)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		a, b := sorted[i], sorted[j]
+		if a.NumberOfTickets != b.NumberOfTickets {
+			return a.NumberOfTickets > b.NumberOfTickets
+		}
+		if a.Total != b.Total {
+			return a.Total > b.Total
+		}
+		return a.ID < b.ID
+	})
+	if len(sorted) > top {
+		sorted = sorted[:top]
+	}
+
+	orders := make([]OrderStats, 0, len(sorted))
+	for _, booking := range sorted {
+		orders = append(orders, OrderStats{
+			ID:        booking.ID,
+			FirstName: booking.FirstName,
+			LastName:  booking.LastName,
+			Email:     booking.Email,
+			Tickets:   booking.NumberOfTickets,
+			Total:     booking.Total,
+			BookedAt:  booking.BookedAt,
+		})
+	}
+	return orders
+}
+
+// round2 rounds to two decimals, which is all a report needs
+func round2(x float64) float64 {
+	return math.Round(x*100) / 100
+}
@@ -0,0 +1,199 @@
+package booking
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// statsBooking is a booking of tickets by email at bookedAt, zero for undated
+func statsBooking(id uint, email string, tickets uint, total int64, bookedAt time.Time) UserData {
+	return UserData{ID: id, FirstName: "Guest", LastName: "Number", Email: email, NumberOfTickets: tickets, Total: total, BookedAt: bookedAt}
+}
+
+func TestComputeStatsTotals(t *testing.T) {
+	at := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name           string
+		tickets        uint
+		bookings       []UserData
+		sold           uint
+		remaining      uint
+		percentSold    float64
+		averageTickets float64
+		revenue        int64
+	}{
+		{"no bookings", 50, nil, 0, 50, 0, 0, 0},
+		{"one booking", 50, []UserData{statsBooking(1, "a@example.com", 5, 2500, at)},
+			5, 45, 10, 5, 2500},
+		{"rounded to two decimals", 3, []UserData{
+			statsBooking(1, "a@example.com", 1, 100, at),
+		}, 1, 2, 33.33, 1, 100},
+		{"uneven average", 100, []UserData{
+			statsBooking(1, "a@example.com", 1, 100, at),
+			statsBooking(2, "b@example.com", 1, 100, at),
+			statsBooking(3, "c@example.com", 2, 200, at),
+		}, 4, 96, 4, 1.33, 400},
+		{"sold out", 4, []UserData{
+			statsBooking(1, "a@example.com", 4, 400, at),
+		}, 4, 0, 100, 4, 400},
+		{"oversold", 2, []UserData{
+			statsBooking(1, "a@example.com", 3, 300, at),
+		}, 3, 0, 150, 3, 300},
+		{"no tickets", 0, []UserData{
+			statsBooking(1, "a@example.com", 1, 100, at),
+		}, 1, 0, 0, 1, 100},
+	}
+	for _, test := range tests {
+		event := Event{ID: "stats", Name: "Stats", Tickets: test.tickets, Currency: "EUR"}
+		stats := ComputeStats(event, test.bookings, StatsOptions{Location: time.UTC})
+		if stats.ConferenceID != "stats" || stats.Name != "Stats" || stats.Currency != "EUR" || stats.Tickets != test.tickets {
+			t.Errorf("%v: event not copied: %+v", test.name, stats)
+		}
+		if stats.Bookings != len(test.bookings) {
+			t.Errorf("%v: bookings = %v, want %v", test.name, stats.Bookings, len(test.bookings))
+		}
+		if stats.TicketsSold != test.sold || stats.TicketsRemaining != test.remaining {
+			t.Errorf("%v: sold %v, remaining %v, want %v and %v", test.name, stats.TicketsSold, stats.TicketsRemaining, test.sold, test.remaining)
+		}
+		if stats.PercentSold != test.percentSold {
+			t.Errorf("%v: percent sold = %v, want %v", test.name, stats.PercentSold, test.percentSold)
+		}
+		if stats.AverageTickets != test.averageTickets {
+			t.Errorf("%v: average tickets = %v, want %v", test.name, stats.AverageTickets, test.averageTickets)
+		}
+		if stats.Revenue != test.revenue {
+			t.Errorf("%v: revenue = %v, want %v", test.name, stats.Revenue, test.revenue)
+		}
+	}
+}
+
+func TestComputeStatsTopDomains(t *testing.T) {
+	bookings := []UserData{
+		statsBooking(1, "a@example.com", 2, 0, time.Time{}),
+		statsBooking(2, "b@EXAMPLE.com", 1, 0, time.Time{}),
+		statsBooking(3, "c@big.org", 3, 0, time.Time{}),
+		statsBooking(4, "d@one.net", 1, 0, time.Time{}),
+		statsBooking(5, "e@two.net", 1, 0, time.Time{}),
+		statsBooking(6, "f@two.net", 0, 0, time.Time{}),
+	}
+	tests := []struct {
+		top  int
+		want []DomainCount
+	}{
+		{2, []DomainCount{
+			{Domain: "example.com", Bookings: 2, Tickets: 3},
+			{Domain: "big.org", Bookings: 1, Tickets: 3},
+		}},
+		// Ties on tickets go to more bookings, then to the domain name
+		{0, []DomainCount{
+			{Domain: "example.com", Bookings: 2, Tickets: 3},
+			{Domain: "big.org", Bookings: 1, Tickets: 3},
+			{Domain: "two.net", Bookings: 2, Tickets: 1},
+			{Domain: "one.net", Bookings: 1, Tickets: 1},
+		}},
+	}
+	for _, test := range tests {
+		stats := ComputeStats(Event{Tickets: 100}, bookings, StatsOptions{Top: test.top, Location: time.UTC})
+		if !reflect.DeepEqual(stats.TopEmailDomains, test.want) {
+			t.Errorf("top %v: got %+v, want %+v", test.top, stats.TopEmailDomains, test.want)
+		}
+	}
+}
+
+func TestComputeStatsPeriods(t *testing.T) {
+	bookings := []UserData{
+		statsBooking(1, "a@example.com", 1, 0, time.Date(2024, 3, 2, 10, 15, 0, 0, time.UTC)),
+		statsBooking(2, "b@example.com", 2, 0, time.Date(2024, 3, 1, 23, 30, 0, 0, time.UTC)),
+		statsBooking(3, "c@example.com", 3, 0, time.Date(2024, 3, 2, 10, 45, 0, 0, time.UTC)),
+		statsBooking(4, "d@example.com", 4, 0, time.Time{}),
+		statsBooking(5, "e@example.com", 5, 0, time.Date(2024, 3, 1, 23, 59, 0, 0, time.UTC)),
+	}
+	cet := time.FixedZone("CET", 3600)
+	tests := []struct {
+		name     string
+		location *time.Location
+		days     []PeriodCount
+		hours    []PeriodCount
+	}{
+		{"UTC", time.UTC,
+			[]PeriodCount{
+				{Start: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), Bookings: 2, Tickets: 7},
+				{Start: time.Date(2024, 3, 2, 0, 0, 0, 0, time.UTC), Bookings: 2, Tickets: 4},
+			},
+			[]PeriodCount{
+				{Start: time.Date(2024, 3, 1, 23, 0, 0, 0, time.UTC), Bookings: 2, Tickets: 7},
+				{Start: time.Date(2024, 3, 2, 10, 0, 0, 0, time.UTC), Bookings: 2, Tickets: 4},
+			}},
+		// An hour east of UTC the late bookings of March 1 fall on March 2
+		{"UTC+1", cet,
+			[]PeriodCount{
+				{Start: time.Date(2024, 3, 2, 0, 0, 0, 0, cet), Bookings: 4, Tickets: 11},
+			},
+			[]PeriodCount{
+				{Start: time.Date(2024, 3, 2, 0, 0, 0, 0, cet), Bookings: 2, Tickets: 7},
+				{Start: time.Date(2024, 3, 2, 11, 0, 0, 0, cet), Bookings: 2, Tickets: 4},
+			}},
+	}
+	for _, test := range tests {
+		stats := ComputeStats(Event{Tickets: 100}, bookings, StatsOptions{Location: test.location})
+		if stats.UndatedBookings != 1 {
+			t.Errorf("%v: undated bookings = %v, want 1", test.name, stats.UndatedBookings)
+		}
+		if !equalPeriods(stats.BookingsPerDay, test.days) {
+			t.Errorf("%v: per day got %+v, want %+v", test.name, stats.BookingsPerDay, test.days)
+		}
+		if !equalPeriods(stats.BookingsPerHour, test.hours) {
+			t.Errorf("%v: per hour got %+v, want %+v", test.name, stats.BookingsPerHour, test.hours)
+		}
+	}
+}
+
+func TestComputeStatsUndatedBookings(t *testing.T) {
+	bookings := []UserData{
+		statsBooking(1, "a@example.com", 2, 200, time.Time{}),
+		statsBooking(2, "b@example.com", 1, 100, time.Time{}),
+	}
+	stats := ComputeStats(Event{Tickets: 10}, bookings, StatsOptions{Location: time.UTC})
+	if stats.UndatedBookings != 2 || len(stats.BookingsPerDay) != 0 || len(stats.BookingsPerHour) != 0 {
+		t.Errorf("undated %v, per day %+v, per hour %+v, want 2 and no periods",
+			stats.UndatedBookings, stats.BookingsPerDay, stats.BookingsPerHour)
+	}
+	// Undated bookings still count everywhere else
+	if stats.TicketsSold != 3 || stats.Revenue != 300 || len(stats.TopEmailDomains) != 1 || len(stats.LargestOrders) != 2 {
+		t.Errorf("undated bookings left out of the totals: %+v", stats)
+	}
+}
+
+func TestComputeStatsLargestOrders(t *testing.T) {
+	bookings := []UserData{
+		statsBooking(1, "a@example.com", 2, 100, time.Time{}),
+		statsBooking(2, "b@example.com", 4, 100, time.Time{}),
+		statsBooking(3, "c@example.com", 2, 300, time.Time{}),
+		statsBooking(4, "d@example.com", 2, 100, time.Time{}),
+	}
+	stats := ComputeStats(Event{Tickets: 100}, bookings, StatsOptions{Top: 3, Location: time.UTC})
+	var got []uint
+	for _, order := range stats.LargestOrders {
+		got = append(got, order.ID)
+	}
+	if want := []uint{2, 3, 1}; !reflect.DeepEqual(got, want) {
+		t.Errorf("largest orders %v, want %v", got, want)
+	}
+	if bookings[0].ID != 1 || bookings[1].ID != 2 {
+		t.Error("ranking reordered the bookings passed in")
+	}
+}
+
+// equalPeriods compares periods by the instant they start at
+func equalPeriods(got, want []PeriodCount) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	for i := range got {
+		if !got[i].Start.Equal(want[i].Start) || got[i].Bookings != want[i].Bookings || got[i].Tickets != want[i].Tickets {
+			return false
+		}
+	}
+	return true
+}
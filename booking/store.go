@@ -0,0 +1,180 @@
+package booking
+
+import (
+	"booking-app/internal/atomicfile"
+	"errors"
+	"os"
+	"sync"
+)
+
+// Store persists the bookings of a conference.
+// Implementations must be safe for use by multiple goroutines.
+type Store interface {
+	// Save adds a new booking or replaces the booking with the same ID
+	Save(booking UserData) error
+	// List returns all stored bookings in the order they were first saved
+	List() ([]UserData, error)
+	// Get returns the booking with the given ID or ErrBookingNotFound
+	Get(id uint) (UserData, error)
+	// Delete removes the booking with the given ID or returns ErrBookingNotFound
+	Delete(id uint) error
+}
+
+// MemoryStore keeps bookings in memory only; they are lost when the process exits
+type MemoryStore struct {
+	mu       sync.Mutex
+	bookings []UserData
+}
+
+// NewMemoryStore creates an empty in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{bookings: make([]UserData, 0)}
+}
+
+// Save adds or replaces a booking
+func (s *MemoryStore) Save(booking UserData) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.bookings = saveBooking(s.bookings, booking)
+	return nil
+}
+
+// List returns a copy of all bookings
+func (s *MemoryStore) List() ([]UserData, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	bookings := make([]UserData, len(s.bookings))
+	copy(bookings, s.bookings)
+	return bookings, nil
+}
+
+// Get returns the booking with the given ID
+func (s *MemoryStore) Get(id uint) (UserData, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return findBooking(s.bookings, id)
+}
+
+// Delete removes the booking with the given ID
+func (s *MemoryStore) Delete(id uint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	bookings, err := deleteBooking(s.bookings, id)
+	if err != nil {
+		return err
+	}
+	s.bookings = bookings
+	return nil
+}
+
+// FileStore keeps bookings in a JSON file so they survive restarts.
+// The whole file is rewritten on every change through a temporary file
+// and a rename, so a crash never leaves a half-written file behind.
+type FileStore struct {
+	mu       sync.Mutex
+	path     string
+	bookings []UserData
+}
+
+// NewFileStore opens the JSON file at path, loading any bookings it already
+// contains. The file is created on the first save if it does not exist.
+// An empty or damaged file is an error rather than an empty start.
+func NewFileStore(path string) (*FileStore, error) {
+	s := &FileStore{path: path, bookings: make([]UserData, 0)}
+
+	err := atomicfile.ReadJSON(path, &s.bookings)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Save adds or replaces a booking and writes the file
+func (s *FileStore) Save(booking UserData) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.write(saveBooking(copyBookings(s.bookings), booking))
+}
+
+// List returns a copy of all bookings
+func (s *FileStore) List() ([]UserData, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return copyBookings(s.bookings), nil
+}
+
+// Get returns the booking with the given ID
+func (s *FileStore) Get(id uint) (UserData, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return findBooking(s.bookings, id)
+}
+
+// Delete removes the booking with the given ID and writes the file
+func (s *FileStore) Delete(id uint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	bookings, err := deleteBooking(copyBookings(s.bookings), id)
+	if err != nil {
+		return err
+	}
+	return s.write(bookings)
+}
+
+// write persists bookings to disk and only then makes them the current state
+func (s *FileStore) write(bookings []UserData) error {
+	if err := atomicfile.WriteJSON(s.path, bookings); err != nil {
+		return err
+	}
+	s.bookings = bookings
+	return nil
+}
+
+// copyBookings returns a copy of bookings that can be modified safely
+func copyBookings(bookings []UserData) []UserData {
+	bookingsCopy := make([]UserData, len(bookings))
+	copy(bookingsCopy, bookings)
+	return bookingsCopy
+}
+
+// saveBooking replaces the booking with the same ID or appends it
+func saveBooking(bookings []UserData, booking UserData) []UserData {
+	for i := range bookings {
+		if bookings[i].ID == booking.ID {
+			bookings[i] = booking
+			return bookings
+		}
+	}
+	return append(bookings, booking)
+}
+
+// findBooking looks up a booking by ID
+func findBooking(bookings []UserData, id uint) (UserData, error) {
+	for _, booking := range bookings {
+		if booking.ID == id {
+			return booking, nil
+		}
+	}
+	return UserData{}, ErrBookingNotFound
+}
+
+// deleteBooking removes the booking with the given ID from bookings
+func deleteBooking(bookings []UserData, id uint) ([]UserData, error) {
+	for i, booking := range bookings {
+		if booking.ID == id {
+			return append(bookings[:i], bookings[i+1:]...), nil
+		}
+	}
+	return nil, ErrBookingNotFound
+}
@@ -0,0 +1,59 @@
+package booking
+
+import (
+	"errors"
+	"fmt"
+)
+
+// DefaultTierID is the tier of events that do not define their own tiers
+const DefaultTierID = "general"
+
+// ErrUnknownTier is returned when an order names a tier the event does not sell
+var ErrUnknownTier = errors.New("booking: unknown ticket tier")
+
+// Tier is one kind of ticket, such as General, VIP or Student,
+// with its own price and number of tickets
+type Tier struct {
+	ID      string `json:"id"`
+	Name    string `json:"name"`
+	Price   int64  `json:"price"` // in minor units of the event currency, e.g. cents
+	Tickets uint   `json:"tickets"`
+}
+
+// TicketOrder asks for a number of tickets of one tier
+type TicketOrder struct {
+	Tier     string `json:"tier"`
+	Quantity uint   `json:"quantity"`
+}
+
+// LineItem records the tickets of one tier in a booking at the price paid
+type LineItem struct {
+	Tier      string `json:"tier"`
+	Quantity  uint   `json:"quantity"`
+	UnitPrice int64  `json:"unitPrice"`
+	Total     int64  `json:"total"`
+}
+
+// TicketTiers returns the tiers sold for the event. Events without tiers
+// sell all of their tickets for free in a single General tier.
+func (e Event) TicketTiers() []Tier {
+	if len(e.Tiers) > 0 {
+		return e.Tiers
+	}
+	return []Tier{{ID: DefaultTierID, Name: "General", Tickets: e.Tickets}}
+}
+
+// FormatAmount formats an amount in minor units, e.g. 1250 "EUR" as "EUR 12.50".
+// Amounts without a currency are free events and shown as plain numbers.
+func FormatAmount(amount int64, currency string) string {
+	sign := ""
+	if amount < 0 {
+		sign = "-"
+		amount = -amount
+	}
+	formatted := fmt.Sprintf("%v%d.%02d", sign, amount/100, amount%100)
+	if currency == "" {
+		return formatted
+	}
+	return currency + " " + formatted
+}
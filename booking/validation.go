@@ -0,0 +1,54 @@
+package booking
+
+import "strings"
+
+// Codes identifying which validation rule a field broke
+const (
+	CodeTooShort           = "too_short"
+	CodeTooLong            = "too_long"
+	CodeInvalidCharacters  = "invalid_characters"
+	CodeInvalidPunctuation = "invalid_punctuation"
+	CodeInvalidEmail       = "invalid_email"
+	CodeBlockedEmail       = "blocked_email"
+	CodeInvalidTicket      = "invalid_ticket_number"
+	CodeNotEnoughTickets   = "not_enough_tickets"
+	CodeTicketLimit        = "ticket_limit_exceeded"
+	CodeUnknownTier        = "unknown_tier"
+	CodeInvalidPromo       = "invalid_promo_code"
+)
+
+// FieldError describes a single validation rule broken by one field
+type FieldError struct {
+	Field   string      `json:"field"`
+	Code    string      `json:"code"`
+	Message string      `json:"message"`
+	Value   interface{} `json:"value"`
+}
+
+// ValidationError holds every field error found while validating a booking.
+// Use errors.As to get at the individual field errors.
+type ValidationError struct {
+	Errors []FieldError `json:"errors"`
+}
+
+// Add records a field error
+func (e *ValidationError) Add(field string, code string, message string, value interface{}) {
+	e.Errors = append(e.Errors, FieldError{Field: field, Code: code, Message: message, Value: value})
+}
+
+// Err returns e as an error if any field error was recorded, otherwise nil
+func (e *ValidationError) Err() error {
+	if len(e.Errors) == 0 {
+		return nil
+	}
+	return e
+}
+
+// Error joins the messages of all field errors
+func (e *ValidationError) Error() string {
+	messages := make([]string, 0, len(e.Errors))
+	for _, fieldError := range e.Errors {
+		messages = append(messages, fieldError.Message)
+	}
+	return "validation failed: " + strings.Join(messages, "; ")
+}
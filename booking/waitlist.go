@@ -0,0 +1,373 @@
+package booking
+
+import (
+	"booking-app/internal/atomicfile"
+	"errors"
+	"os"
+	"sync"
+	"time"
+)
+
+// DefaultOfferWindow is how long a waitlist offer holds its tickets unless configured otherwise
+const DefaultOfferWindow = 24 * time.Hour
+
+// Errors returned by the waitlist
+var (
+	ErrTicketsAvailable = errors.New("booking: tickets are still available, book them instead")
+	ErrWaitlistNotFound = errors.New("booking: waitlist entry not found")
+	ErrNoOffer          = errors.New("booking: waitlist entry has no pending offer")
+	ErrWaitlistTooMany  = errors.New("booking: more tickets requested than the tier has")
+)
+
+// WaitlistEntry is a request for tickets that could not be booked because the
+// tier was sold out. Entries are served first in, first out; when tickets are
+// freed the next entry gets an offer: a Hold on the tickets until OfferExpires.
+type WaitlistEntry struct {
+	ID              uint      `json:"id"`
+	FirstName       string    `json:"firstName"`
+	LastName        string    `json:"lastName"`
+	Email           string    `json:"email"`
+	Tier            string    `json:"tier"`
+	NumberOfTickets uint      `json:"numberOfTickets"`
+	JoinedAt        time.Time `json:"joinedAt"`
+	HoldID          uint      `json:"holdId,omitempty"`
+	OfferExpires    time.Time `json:"offerExpires"`
+}
+
+// HasOffer reports whether tickets are currently held for the entry
+func (e WaitlistEntry) HasOffer() bool {
+	return e.HoldID != 0
+}
+
+// WaitlistStore persists the waitlist of a conference.
+// Implementations must be safe for use by multiple goroutines.
+type WaitlistStore interface {
+	// Save replaces the stored waitlist with entries
+	Save(entries []WaitlistEntry) error
+	// List returns the stored entries in the order they will be served
+	List() ([]WaitlistEntry, error)
+}
+
+// MemoryWaitlistStore keeps the waitlist in memory only
+type MemoryWaitlistStore struct {
+	mu      sync.Mutex
+	entries []WaitlistEntry
+}
+
+// NewMemoryWaitlistStore creates an empty in-memory waitlist store
+func NewMemoryWaitlistStore() *MemoryWaitlistStore {
+	return &MemoryWaitlistStore{entries: make([]WaitlistEntry, 0)}
+}
+
+// Save replaces the stored entries
+func (s *MemoryWaitlistStore) Save(entries []WaitlistEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.entries = copyWaitlist(entries)
+	return nil
+}
+
+// List returns a copy of the stored entries
+func (s *MemoryWaitlistStore) List() ([]WaitlistEntry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return copyWaitlist(s.entries), nil
+}
+
+// FileWaitlistStore keeps the waitlist in a JSON file so it survives restarts.
+// Like FileStore it rewrites the whole file through a temporary file.
+type FileWaitlistStore struct {
+	mu      sync.Mutex
+	path    string
+	entries []WaitlistEntry
+}
+
+// NewFileWaitlistStore opens the JSON file at path, loading the entries it
+// already contains. The file is created when the waitlist first changes.
+// An empty or damaged file is an error rather than an empty start.
+func NewFileWaitlistStore(path string) (*FileWaitlistStore, error) {
+	s := &FileWaitlistStore{path: path, entries: make([]WaitlistEntry, 0)}
+
+	err := atomicfile.ReadJSON(path, &s.entries)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Save writes the entries to the file and only then makes them the stored entries
+func (s *FileWaitlistStore) Save(entries []WaitlistEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries = copyWaitlist(entries)
+	if err := atomicfile.WriteJSON(s.path, entries); err != nil {
+		return err
+	}
+	s.entries = entries
+	return nil
+}
+
+// List returns a copy of the stored entries
+func (s *FileWaitlistStore) List() ([]WaitlistEntry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return copyWaitlist(s.entries), nil
+}
+
+// SetWaitlistStore replaces the in-memory waitlist of the conference, for
+// example with a FileWaitlistStore, and loads the entries it contains.
+// Pending offers hold their tickets again until they expire; offers that
+// expired in the meantime are dropped like any other expired offer.
+// New offers are only made once SetWaitlistOffers says who to tell.
+func (c *Conference) SetWaitlistStore(store WaitlistStore) error {
+	entries, err := store.List()
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.waitlistStore = store
+	c.waitlist = make([]WaitlistEntry, 0, len(entries))
+	c.lastWaitlistID = 0
+	now := time.Now()
+	for _, entry := range entries {
+		if entry.ID > c.lastWaitlistID {
+			c.lastWaitlistID = entry.ID
+		}
+		if entry.HasOffer() {
+			if !now.Before(entry.OfferExpires) {
+				continue
+			}
+			hold, err := c.reserveLocked([]TicketOrder{{Tier: entry.Tier, Quantity: entry.NumberOfTickets}}, entry.OfferExpires.Sub(now), entry.ID)
+			if err != nil {
+				// The tickets are gone; the entry waits for the next offer
+				entry.HoldID = 0
+				entry.OfferExpires = time.Time{}
+			} else {
+				entry.HoldID = hold.ID
+			}
+		}
+		c.waitlist = append(c.waitlist, entry)
+	}
+	return nil
+}
+
+// SetWaitlistOffers configures how long offers hold their tickets and the
+// function told about each new offer, e.g. to email the person waiting.
+// The function runs in its own goroutine. Tickets that are already free,
+// for example when the waitlist was loaded from a store, are offered now.
+func (c *Conference) SetWaitlistOffers(window time.Duration, onOffer func(Event, WaitlistEntry)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.offerWindow = window
+	c.onWaitlistOffer = onOffer
+	c.offerFreedTickets()
+}
+
+// JoinWaitlist adds a request for tickets of a tier that does not have enough
+// tickets left. The entry is returned with its position-defining ID.
+func (c *Conference) JoinWaitlist(firstName string, lastName string, email string, tierID string, userTickets uint) (WaitlistEntry, error) {
+	if userTickets == 0 {
+		return WaitlistEntry{}, ErrNoTickets
+	}
+	tier, err := c.Tier(tierID)
+	if err != nil {
+		return WaitlistEntry{}, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.salesClosed {
+		return WaitlistEntry{}, ErrSalesClosed
+	}
+	if userTickets <= c.forSale(tier.ID) {
+		return WaitlistEntry{}, ErrTicketsAvailable
+	}
+	// Freed tickets are kept for the head of the line, so an entry that can
+	// never be served would block everyone behind it
+	if userTickets > tier.Tickets {
+		return WaitlistEntry{}, ErrWaitlistTooMany
+	}
+
+	entry := WaitlistEntry{
+		ID:              c.lastWaitlistID + 1,
+		FirstName:       NormalizeName(firstName),
+		LastName:        NormalizeName(lastName),
+		Email:           email,
+		Tier:            tier.ID,
+		NumberOfTickets: userTickets,
+		JoinedAt:        time.Now(),
+	}
+	if err := c.checkTicketLimits(UserData{FirstName: entry.FirstName, LastName: entry.LastName, Email: entry.Email, NumberOfTickets: userTickets}, true); err != nil {
+		return WaitlistEntry{}, err
+	}
+	// Only queue the entry once it is safely stored
+	if err := c.waitlistStore.Save(append(copyWaitlist(c.waitlist), entry)); err != nil {
+		return WaitlistEntry{}, err
+	}
+	c.lastWaitlistID = entry.ID
+	c.waitlist = append(c.waitlist, entry)
+	return entry, nil
+}
+
+// Waitlist returns all entries in the order they will be served
+func (c *Conference) Waitlist() []WaitlistEntry {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return copyWaitlist(c.waitlist)
+}
+
+// LeaveWaitlist removes an entry, releasing the tickets of a pending offer
+func (c *Conference) LeaveWaitlist(id uint) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	i := c.findWaitlistEntry(id)
+	if i < 0 {
+		return ErrWaitlistNotFound
+	}
+	if err := c.waitlistStore.Save(c.waitlistWithout(i)); err != nil {
+		return err
+	}
+	c.removeWaitlistEntry(i)
+	c.offerFreedTickets()
+	return nil
+}
+
+// AcceptOffer turns the pending offer of a waitlist entry into a booking.
+// The entry is removed from the stored waitlist first, so an accepted offer
+// is never made again after a restart.
+func (c *Conference) AcceptOffer(id uint) (UserData, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	i := c.findWaitlistEntry(id)
+	if i < 0 {
+		return UserData{}, ErrWaitlistNotFound
+	}
+	entry := c.waitlist[i]
+	hold := c.findHold(entry.HoldID)
+	if !entry.HasOffer() || hold < 0 || !time.Now().Before(c.holds[hold].Expires) {
+		return UserData{}, ErrNoOffer
+	}
+
+	if err := c.waitlistStore.Save(c.waitlistWithout(i)); err != nil {
+		return UserData{}, err
+	}
+	userData, err := c.confirmLocked(hold, Order{
+		FirstName: entry.FirstName,
+		LastName:  entry.LastName,
+		Email:     entry.Email,
+	})
+	if err != nil {
+		c.saveWaitlist()
+		return UserData{}, err
+	}
+
+	c.waitlist = append(c.waitlist[:i], c.waitlist[i+1:]...)
+	return userData, nil
+}
+
+// offerFreedTickets offers available tickets to the waitlist, first in, first out,
+// by holding them for the offer window. Expired offers are dropped by the hold
+// expiry goroutine, which then offers the tickets to the next in line.
+// Within a tier an entry never gets an offer before the entries ahead of it,
+// so a large request at the front is not skipped for a smaller one behind it.
+// Callers must hold c.mu.
+func (c *Conference) offerFreedTickets() {
+	if c.salesClosed {
+		return
+	}
+
+	offered := false
+	blocked := make(map[string]bool)
+	for i := range c.waitlist {
+		entry := &c.waitlist[i]
+		if entry.HasOffer() || blocked[entry.Tier] {
+			continue
+		}
+		hold, err := c.reserveLocked([]TicketOrder{{Tier: entry.Tier, Quantity: entry.NumberOfTickets}}, c.offerWindow, entry.ID)
+		if err != nil {
+			blocked[entry.Tier] = true
+			continue
+		}
+
+		entry.HoldID = hold.ID
+		entry.OfferExpires = hold.Expires
+		offered = true
+		if c.onWaitlistOffer != nil {
+			go c.onWaitlistOffer(c.event, *entry)
+		}
+	}
+	if offered {
+		c.saveWaitlist()
+	}
+}
+
+// removeWaitlistEntry deletes entry i, releasing the hold of a pending offer.
+// Callers must hold c.mu.
+func (c *Conference) removeWaitlistEntry(i int) {
+	entry := c.waitlist[i]
+	if hold := c.findHold(entry.HoldID); entry.HasOffer() && hold >= 0 {
+		c.releaseHold(hold)
+	}
+	c.waitlist = append(c.waitlist[:i], c.waitlist[i+1:]...)
+}
+
+// findWaitlistEntry returns the index of the entry with the given ID, or -1.
+// Callers must hold c.mu.
+func (c *Conference) findWaitlistEntry(id uint) int {
+	for i, entry := range c.waitlist {
+		if entry.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// waitingFor reports whether anyone without an offer is waiting for the tier.
+// Callers must hold c.mu.
+func (c *Conference) waitingFor(tierID string) bool {
+	for _, entry := range c.waitlist {
+		if entry.Tier == tierID && !entry.HasOffer() {
+			return true
+		}
+	}
+	return false
+}
+
+// waitlistWithout returns a copy of the waitlist without entry i.
+// Callers must hold c.mu.
+func (c *Conference) waitlistWithout(i int) []WaitlistEntry {
+	waitlist := make([]WaitlistEntry, 0, len(c.waitlist))
+	waitlist = append(waitlist, c.waitlist[:i]...)
+	return append(waitlist, c.waitlist[i+1:]...)
+}
+
+// saveWaitlist stores the current waitlist after a change that cannot fail,
+// such as an offer being made or expiring. If the store fails, the next
+// change writes the waitlist again; until then a restart may repeat an
+// offer or bring back an entry whose offer expired.
+// Callers must hold c.mu.
+func (c *Conference) saveWaitlist() {
+	_ = c.waitlistStore.Save(c.waitlist)
+}
+
+// copyWaitlist returns a copy of entries that can be modified safely
+func copyWaitlist(entries []WaitlistEntry) []WaitlistEntry {
+	entriesCopy := make([]WaitlistEntry, len(entries))
+	copy(entriesCopy, entries)
+	return entriesCopy
+}
@@ -0,0 +1,186 @@
+package booking
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func TestWaitlistKeepsFreedTicketsForTheHeadOfTheLine(t *testing.T) {
+	conference := NewConference(Event{ID: "fifo", Name: "FIFO", Tickets: 4})
+	first, err := conference.Book(2, "Ada", "Lovelace", "ada@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := conference.Book(2, "Alan", "Turing", "alan@example.com"); err != nil {
+		t.Fatal(err)
+	}
+	entry, err := conference.JoinWaitlist("Grace", "Hopper", "grace@example.com", DefaultTierID, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Two tickets are freed, which is not enough for the entry waiting
+	if _, err := conference.Cancel(first.ID); err != nil {
+		t.Fatal(err)
+	}
+	if remaining := conference.Remaining(); remaining != 0 {
+		t.Errorf("Remaining = %v while someone waits, want 0", remaining)
+	}
+	if _, err := conference.Book(1, "Edsger", "Dijkstra", "edsger@example.com"); !errors.Is(err, ErrNotEnoughTickets) {
+		t.Errorf("Book ahead of the waitlist = %v, want ErrNotEnoughTickets", err)
+	}
+	if _, err := conference.Reserve([]TicketOrder{{Tier: DefaultTierID, Quantity: 1}}, 0); !errors.Is(err, ErrNotEnoughTickets) {
+		t.Errorf("Reserve ahead of the waitlist = %v, want ErrNotEnoughTickets", err)
+	}
+
+	// Once the entry leaves, the tickets are on sale again
+	if err := conference.LeaveWaitlist(entry.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := conference.Book(2, "Edsger", "Dijkstra", "edsger@example.com"); err != nil {
+		t.Errorf("Book after the waitlist emptied: %v", err)
+	}
+}
+
+func TestWaitlistSurvivesRestart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "waitlist.json")
+	event := Event{ID: "restart", Name: "Restart", Tickets: 2}
+
+	open := func(store Store) *Conference {
+		t.Helper()
+		conference, err := OpenConference(event, store)
+		if err != nil {
+			t.Fatal(err)
+		}
+		waitlist, err := NewFileWaitlistStore(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := conference.SetWaitlistStore(waitlist); err != nil {
+			t.Fatal(err)
+		}
+		return conference
+	}
+
+	store := NewMemoryStore()
+	conference := open(store)
+	booked, err := conference.Book(2, "Ada", "Lovelace", "ada@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry, err := conference.JoinWaitlist("Grace", "Hopper", "grace@example.com", DefaultTierID, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conference = open(store)
+	waitlist := conference.Waitlist()
+	if len(waitlist) != 1 || waitlist[0].ID != entry.ID || waitlist[0].Email != entry.Email {
+		t.Fatalf("Waitlist after restart = %+v, want the entry of %v", waitlist, entry.Email)
+	}
+
+	if _, err := conference.Cancel(booked.ID); err != nil {
+		t.Fatal(err)
+	}
+	offered := conference.Waitlist()
+	if len(offered) != 1 || !offered[0].HasOffer() {
+		t.Fatalf("Waitlist after cancelling = %+v, want an offer", offered)
+	}
+
+	// The offer holds its tickets again after another restart
+	conference = open(store)
+	if remaining := conference.Remaining(); remaining != 0 {
+		t.Errorf("Remaining after restart = %v, want 0 while the offer is pending", remaining)
+	}
+	if _, err := conference.AcceptOffer(entry.ID); err != nil {
+		t.Fatalf("AcceptOffer after restart: %v", err)
+	}
+	if waitlist := open(store).Waitlist(); len(waitlist) != 0 {
+		t.Errorf("Waitlist after accepting = %+v, want it empty", waitlist)
+	}
+}
+
+func TestJoinWaitlistRejectsRequestsThatCanNeverBeServed(t *testing.T) {
+	conference := NewConference(Event{ID: "huge", Name: "Huge", Tickets: 10})
+	booked, err := conference.Book(4, "Ada", "Lovelace", "ada@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := conference.Book(6, "Alan", "Turing", "alan@example.com"); err != nil {
+		t.Fatal(err)
+	}
+	conference.SetTicketLimits(TicketLimits{PerEmail: 4})
+
+	if _, err := conference.JoinWaitlist("Eve", "Evans", "eve@example.com", DefaultTierID, 1000000); !errors.Is(err, ErrWaitlistTooMany) {
+		t.Errorf("JoinWaitlist for more tickets than the tier has = %v, want ErrWaitlistTooMany", err)
+	}
+	if _, err := conference.JoinWaitlist("Ada", "Lovelace", "ada@example.com", DefaultTierID, 1); !errors.Is(err, ErrTicketLimit) {
+		t.Errorf("JoinWaitlist past the per-email limit = %v, want ErrTicketLimit", err)
+	}
+	if _, err := conference.JoinWaitlist("Grace", "Hopper", "grace@example.com", DefaultTierID, 3); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := conference.JoinWaitlist("Grace", "Hopper", "grace+2@example.com", DefaultTierID, 2); !errors.Is(err, ErrTicketLimit) {
+		t.Errorf("second JoinWaitlist past the per-email limit = %v, want ErrTicketLimit", err)
+	}
+	entry, err := conference.JoinWaitlist("Bob", "Brown", "bob@example.com", DefaultTierID, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Freed tickets reach the entries in line
+	if _, err := conference.Cancel(booked.ID); err != nil {
+		t.Fatal(err)
+	}
+	for _, waiting := range conference.Waitlist() {
+		if !waiting.HasOffer() {
+			t.Errorf("entry %v of %v has no offer after 4 tickets were freed", waiting.ID, waiting.Email)
+		}
+	}
+	if _, err := conference.AcceptOffer(entry.ID); err != nil {
+		t.Errorf("AcceptOffer: %v", err)
+	}
+}
+
+func TestWaitlistOfferHoldIsOnlyForTheEntry(t *testing.T) {
+	conference := NewConference(Event{ID: "offer", Name: "Offer", Tickets: 2})
+	booked, err := conference.Book(2, "Ada", "Lovelace", "ada@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry, err := conference.JoinWaitlist("Bob", "Brown", "bob@example.com", DefaultTierID, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := conference.Cancel(booked.ID); err != nil {
+		t.Fatal(err)
+	}
+	offered := conference.Waitlist()
+	if len(offered) != 1 || !offered[0].HasOffer() {
+		t.Fatalf("Waitlist = %+v, want an offer for Bob", offered)
+	}
+	holdID := offered[0].HoldID
+
+	if holds := conference.Holds(); len(holds) != 0 {
+		t.Errorf("Holds = %+v, want the offer left out", holds)
+	}
+	if _, err := conference.Hold(holdID); !errors.Is(err, ErrHoldOffered) {
+		t.Errorf("Hold of the offer = %v, want ErrHoldOffered", err)
+	}
+	eve := Order{FirstName: "Eve", LastName: "Evans", Email: "eve@example.com"}
+	if _, err := conference.Confirm(holdID, eve); !errors.Is(err, ErrHoldOffered) {
+		t.Errorf("Confirm of the offer by someone else = %v, want ErrHoldOffered", err)
+	}
+	if err := conference.Release(holdID); !errors.Is(err, ErrHoldOffered) {
+		t.Errorf("Release of the offer = %v, want ErrHoldOffered", err)
+	}
+
+	userData, err := conference.AcceptOffer(entry.ID)
+	if err != nil {
+		t.Fatalf("AcceptOffer: %v", err)
+	}
+	if userData.Email != "bob@example.com" || userData.NumberOfTickets != 2 {
+		t.Errorf("AcceptOffer booked %v tickets for %v, want 2 for bob@example.com", userData.NumberOfTickets, userData.Email)
+	}
+}
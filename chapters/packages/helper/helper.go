@@ -3,14 +3,140 @@
 // (accessible from other packages).
 package helper
 
-import "strings"
+import (
+	"bufio"
+	"net/mail"
+	"os"
+	"strings"
+)
+
+// EmailRules configures how ValidateUserInput checks email addresses.
+// Strict requires a proper domain with an alphabetic top-level domain;
+// Blocklist holds lower-case domains (e.g. disposable providers) to reject.
+type EmailRules struct {
+	Strict    bool
+	Blocklist map[string]bool
+}
+
+// Email holds the rules used by ValidateUserInput. Callers may change it at startup.
+var Email = EmailRules{Strict: true}
+
+// LoadBlocklist reads one domain per line from a file, skipping blanks and '#' comments
+func LoadBlocklist(path string) (map[string]bool, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	blocklist := map[string]bool{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line != "" && !strings.HasPrefix(line, "#") {
+			blocklist[strings.ToLower(line)] = true
+		}
+	}
+	return blocklist, scanner.Err()
+}
+
+// IsValidEmail checks an address using net/mail and, depending on rules,
+// the shape of its domain and the blocklist.
+// It is a simplified version for this chapter: the application in the
+// repository root uses booking.ValidateEmail, which has three strictness
+// levels and also checks the characters of the local part and the domain.
+func IsValidEmail(email string, rules EmailRules) bool {
+	// Overall and local part length limits from RFC 5321
+	if len(email) > 254 {
+		return false
+	}
+
+	address, err := mail.ParseAddress(email)
+	if err != nil || address.Name != "" || address.Address != email {
+		return false
+	}
+
+	at := strings.LastIndex(email, "@")
+	local, domain := email[:at], strings.ToLower(email[at+1:])
+	if len(local) > 64 {
+		return false
+	}
+
+	if rules.Strict {
+		labels := strings.Split(domain, ".")
+		if len(labels) < 2 {
+			return false
+		}
+		for _, label := range labels {
+			if label == "" || len(label) > 63 || strings.HasPrefix(label, "-") || strings.HasSuffix(label, "-") {
+				return false
+			}
+		}
+		tld := labels[len(labels)-1]
+		if len(tld) < 2 || strings.Trim(tld, "abcdefghijklmnopqrstuvwxyz") != "" {
+			return false
+		}
+	}
+
+	// A blocked domain also blocks all of its subdomains
+	for d := domain; d != ""; {
+		if rules.Blocklist[d] {
+			return false
+		}
+		dot := strings.Index(d, ".")
+		if dot < 0 {
+			break
+		}
+		d = d[dot+1:]
+	}
+	return true
+}
+
+// FieldError describes one broken validation rule.
+// Field names the input, Code identifies the rule and Value is the offending input.
+type FieldError struct {
+	Field   string
+	Code    string
+	Message string
+	Value   interface{}
+}
+
+// ValidationError collects every FieldError found in one call to ValidateUserInput.
+// Because it implements the error interface it can be returned as a plain error
+// and recovered by callers with errors.As.
+type ValidationError struct {
+	Errors []FieldError
+}
+
+// Error joins all field messages into one line
+func (e *ValidationError) Error() string {
+	messages := []string{}
+	for _, fieldError := range e.Errors {
+		messages = append(messages, fieldError.Message)
+	}
+	return strings.Join(messages, "; ")
+}
 
 // ValidateUserInput checks if the user provided valid data for a booking.
-// It returns three booleans corresponding to name, email, and ticket validity.
-func ValidateUserInput(firstName string, lastName string, email string, userTickets uint, remainingTickets uint) (bool, bool, bool) {
-	isValidName := len(firstName) >= 2 && len(lastName) >= 2
-	isValidEmail := strings.Contains(email, "@")
-	isValidTicketNumber := userTickets > 0 && userTickets <= remainingTickets
+// It returns nil if the input is valid, or a *ValidationError listing every problem.
+func ValidateUserInput(firstName string, lastName string, email string, userTickets uint, remainingTickets uint) error {
+	validationErr := &ValidationError{}
+
+	if len(firstName) < 2 {
+		validationErr.Errors = append(validationErr.Errors, FieldError{"firstName", "too_short", "First name is too short.", firstName})
+	}
+	if len(lastName) < 2 {
+		validationErr.Errors = append(validationErr.Errors, FieldError{"lastName", "too_short", "Last name is too short.", lastName})
+	}
+	if !IsValidEmail(email, Email) {
+		validationErr.Errors = append(validationErr.Errors, FieldError{"email", "invalid_email", "Email address is invalid.", email})
+	}
+	if userTickets == 0 || userTickets > remainingTickets {
+		validationErr.Errors = append(validationErr.Errors, FieldError{"numberOfTickets", "invalid_ticket_number", "Number of tickets is invalid.", userTickets})
+	}
 
-	return isValidName, isValidEmail, isValidTicketNumber
+	if len(validationErr.Errors) > 0 {
+		return validationErr
+	}
+	return nil
 }
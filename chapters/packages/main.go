@@ -5,6 +5,7 @@ package main
 
 import (
 	"booking-app/helper" // Importing a local package
+	"errors"
 	"fmt"
 	"strings"
 )
@@ -23,9 +24,9 @@ func main() {
 
 		// Using an exported function from the 'helper' package.
 		// Exported functions must start with a capital letter.
-		isValidName, isValidEmail, isValidTicketNumber := helper.ValidateUserInput(firstName, lastName, email, userTickets, remainingTickets)
+		err := helper.ValidateUserInput(firstName, lastName, email, userTickets, remainingTickets)
 
-		if isValidName && isValidEmail && isValidTicketNumber {
+		if err == nil {
 			bookTicket(userTickets, firstName, lastName, email)
 
 			firstNames := getFirstNames()
@@ -36,14 +37,13 @@ func main() {
 				break
 			}
 		} else {
-			if !isValidName {
-				fmt.Println("First or last name is too short.")
-			}
-			if !isValidEmail {
-				fmt.Println("Email address is missing @ sign.")
-			}
-			if !isValidTicketNumber {
-				fmt.Println("Number of tickets is invalid.")
+			// errors.As unwraps the error into the helper's own type,
+			// giving access to one message per broken rule.
+			var validationErr *helper.ValidationError
+			if errors.As(err, &validationErr) {
+				for _, fieldError := range validationErr.Errors {
+					fmt.Println(fieldError.Message)
+				}
 			}
 			continue
 		}
@@ -0,0 +1,448 @@
+package main
+
+import (
+	"booking-app/booking"
+	"booking-app/notify"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time"
+)
+
+// command is one subcommand of the CLI. run defines the flags of the command
+// on fs, whose usage message is built from the fields here, and returns the
+// exit code of the program: 0 on success, 1 if the command failed and 2 if
+// it was used wrongly.
+type command struct {
+	name string
+	// usage lists the flags and arguments of the command
+	usage   string
+	summary string
+	// description is printed below the usage line in the help of the command
+	description string
+	run         func(env *environment, fs *flag.FlagSet, args []string) int
+}
+
+// commands are listed in this order in the usage message
+var commands = []command{
+	{
+		name:        "book",
+		usage:       "[-conference id -first name -last name -email address -tickets n [-tier id] [-promo code]]",
+		summary:     "book tickets; asks interactively unless the details are given as flags",
+		description: "Without flags the prompt books one order after another until the input ends.",
+		run:         bookCommand,
+	},
+	{
+		name:    "list",
+		usage:   "[-conference id]",
+		summary: "list the bookings of a conference",
+		run:     listCommand,
+	},
+	{
+		name:    "show",
+		usage:   "[-conference id] id|code",
+		summary: "show a booking by ID or confirmation code",
+		run:     showCommand,
+	},
+	{
+		name:        "cancel",
+		usage:       "[-conference id] [-tickets n [-tier id]] [-reason text] id|code",
+		summary:     "cancel all or some tickets of a booking",
+		description: "Without -tickets the whole booking is cancelled. A cancellation notice is emailed.",
+		run:         cancelCommand,
+	},
+	{
+		name:    "import",
+		usage:   "[-conference id] [-tier id] [-dry-run] [-json] file.csv",
+		summary: "book the registrations in a CSV file",
+		description: "Columns: first name, last name, email, tickets and optionally tier and promo code.\n" +
+			"A header row may name the columns in any order; - reads the file from standard input.",
+		run: importCommand,
+	},
+	{
+		name:    "export",
+		usage:   "[-conference id] [-format csv|json|ndjson] [-fields a,b] [-domain d] [-min-tickets n] [-max-tickets n] [-o file]",
+		summary: "write the bookings as CSV, JSON or NDJSON",
+		run:     exportCommand,
+	},
+	{
+		name:    "stats",
+		usage:   "[-conference id] [-top n] [-json]",
+		summary: "report ticket sales, top email domains, bookings over time and largest orders",
+		run:     statsCommand,
+	},
+	{
+		name:    "serve",
+		usage:   "[-addr address]",
+		summary: "run the HTTP JSON API",
+		run:     serveCommand,
+	},
+	{
+		name:    "dead-letters",
+		usage:   "list|redrive",
+		summary: "inspect and resend tickets that could not be delivered",
+		run: func(env *environment, fs *flag.FlagSet, args []string) int {
+			if code, ok := parseArgs(fs, args, 1, 1); !ok {
+				return code
+			}
+			return deadLettersCommand(env.ctx, fs.Args(), env.cfg.Queue.DeadLetters, env.notifier, env.cfg.Queue.Retry)
+		},
+	},
+}
+
+// environment is shared by the commands: the settings, the conferences and,
+// once a command starts them, the ticket deliveries
+type environment struct {
+	ctx        context.Context
+	cfg        config
+	registry   *booking.Registry
+	notifier   notify.Notifier
+	deliveries *notify.Queue
+}
+
+// startDeliveries starts the delivery workers and the waitlist offers on first use
+func (env *environment) startDeliveries() *notify.Queue {
+	if env.deliveries == nil {
+		env.deliveries = notify.NewQueue(env.notifier, env.cfg.Queue)
+		wg.Add(1)
+		go reportDeliveries(env.deliveries)
+		offerWaitlistTickets(env.registry, env.deliveries, env.cfg.OfferWindow)
+	}
+	return env.deliveries
+}
+
+// stopDeliveries sends the queued tickets if deliveries were started
+func (env *environment) stopDeliveries() {
+	if env.deliveries != nil {
+		shutdownDeliveries(env.deliveries, env.cfg.ShutdownTimeout)
+	}
+}
+
+// runCommand runs the command named by the first argument and returns the
+// exit code of the program. Without arguments it serves the API if -serve is
+// set and runs the interactive prompt otherwise, as before there were commands.
+func runCommand(env *environment, args []string) int {
+	name := "book"
+	if env.cfg.Serve != "" {
+		name = "serve"
+	}
+	if len(args) > 0 {
+		name, args = args[0], args[1:]
+	}
+
+	// "help import" shows the same message as "import -h"
+	if name == "help" {
+		if len(args) == 0 {
+			printUsage(os.Stdout)
+			return 0
+		}
+		name, args = args[0], []string{"-h"}
+	}
+
+	cmd, ok := findCommand(name)
+	if !ok {
+		fmt.Fprintf(os.Stderr, "Unknown command %q\n\n", name)
+		printUsage(os.Stderr)
+		return 2
+	}
+	fs := flag.NewFlagSet(cmd.name, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: booking-app [flags] %v %v\n", cmd.name, cmd.usage)
+		if cmd.description != "" {
+			fmt.Fprintln(fs.Output(), cmd.description)
+		}
+		fs.PrintDefaults()
+	}
+	return cmd.run(env, fs, args)
+}
+
+// findCommand returns the command with the given name
+func findCommand(name string) (command, bool) {
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd, true
+		}
+	}
+	return command{}, false
+}
+
+// printUsage lists the commands; the flags shared by all of them follow it
+// in the usage message of the program
+func printUsage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: booking-app [flags] [command] [arguments]")
+	fmt.Fprintln(w, "\nCommands:")
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, cmd := range commands {
+		fmt.Fprintf(tw, "  %v\t%v\n", cmd.name, cmd.summary)
+	}
+	fmt.Fprintf(tw, "  %v\t%v\n", "help", "show the flags of a command")
+	tw.Flush()
+	fmt.Fprintln(w, "\nWithout a command the app books interactively, or serves the API if -serve is set.")
+	fmt.Fprintln(w, "Commands that read or keep bookings across runs need -data.")
+	fmt.Fprintln(w, "Exit codes: 0 success, 1 failure (e.g. a rejected booking), 2 invalid usage.")
+	fmt.Fprintln(w, "Run 'booking-app help <command>' for the flags of a command.")
+}
+
+// parseArgs parses the flags of a command and checks that between min and
+// max arguments are left, or at least min if max is negative. If the command
+// should not run it returns false with the exit code: 0 after -h, 2 otherwise.
+func parseArgs(fs *flag.FlagSet, args []string, min int, max int) (int, bool) {
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0, false
+		}
+		return 2, false
+	}
+	if fs.NArg() < min || (max >= 0 && fs.NArg() > max) {
+		fs.Usage()
+		return 2, false
+	}
+	return 0, true
+}
+
+// requireDataDir checks that -data is set for a command that reads or keeps
+// bookings across runs. Without it every run starts with no bookings and
+// forgets the ones it makes, so the command fails with exit code 2.
+func requireDataDir(env *environment, name string) (int, bool) {
+	if env.cfg.DataDir == "" {
+		fmt.Fprintf(os.Stderr, "Error: %v needs -data; without a data directory no bookings are kept between runs\n", name)
+		return 2, false
+	}
+	return 0, true
+}
+
+// pickConference returns the conference chosen with -conference, or the only one
+func pickConference(registry *booking.Registry, id string) (*booking.Conference, error) {
+	if id != "" {
+		return registry.Get(id)
+	}
+	conferences := registry.List()
+	if len(conferences) != 1 {
+		return nil, fmt.Errorf("there are %v conferences, choose one with -conference", len(conferences))
+	}
+	return conferences[0], nil
+}
+
+// bookCommand books tickets. Given the details as flags it books a single
+// order without asking anything, for scripts; otherwise it runs the
+// interactive prompt until the input ends or the app is interrupted.
+func bookCommand(env *environment, fs *flag.FlagSet, args []string) int {
+	conferenceID := fs.String("conference", "", "ID of the conference; may be left out if there is only one")
+	firstName := fs.String("first", "", "first name")
+	lastName := fs.String("last", "", "last name")
+	email := fs.String("email", "", "email address")
+	userTickets := fs.Uint("tickets", 0, "number of tickets")
+	tierID := fs.String("tier", "", "ticket type (default the first one)")
+	promoCode := fs.String("promo", "", "promo code")
+	if code, ok := parseArgs(fs, args, 0, 0); !ok {
+		return code
+	}
+
+	p := newPrompter(os.Stdin, os.Stdout, env.cfg.PromptRetries)
+	if fs.NFlag() == 0 {
+		return promptCommand(env, p)
+	}
+	if code, ok := requireDataDir(env, fs.Name()); !ok {
+		return code
+	}
+
+	if *firstName == "" || *lastName == "" || *email == "" || *userTickets == 0 {
+		fmt.Fprintln(os.Stderr, "Error: -first, -last, -email and -tickets are needed to book without the prompt")
+		return 2
+	}
+	conference, err := pickConference(env.registry, *conferenceID)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2
+	}
+	if *tierID == "" {
+		*tierID = conference.Tiers()[0].ID
+	}
+
+	orders := []booking.TicketOrder{{Tier: *tierID, Quantity: *userTickets}}
+	if err := validateUserInput(conference, *firstName, *lastName, *email, orders, *promoCode); err != nil {
+		printValidationError(os.Stderr, err)
+		return 1
+	}
+	order := booking.Order{FirstName: *firstName, LastName: *lastName, Email: *email, Items: orders, PromoCode: *promoCode}
+	userData, err := bookTicket(p, conference, order)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	if err := sendTicket(env.startDeliveries(), conference, userData); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// promptCommand runs the interactive prompt in its own goroutine, so an
+// interrupt is noticed even while it is waiting for the user to type
+func promptCommand(env *environment, p *prompter) int {
+	deliveries := env.startDeliveries()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		runPrompt(p, env.registry, deliveries)
+	}()
+
+	select {
+	case <-done:
+	case <-env.ctx.Done():
+		fmt.Println("\nInterrupted, no longer accepting bookings.")
+		env.registry.CloseSales()
+		waitForPromptBooking(done, env.cfg.ShutdownTimeout)
+	}
+	return 0
+}
+
+// waitForPromptBooking waits up to timeout for a booking made just before
+// sales closed to queue its ticket, so the ticket is not lost when the
+// delivery queue shuts down. It returns early if the prompt has ended.
+func waitForPromptBooking(done <-chan struct{}, timeout time.Duration) {
+	queued := make(chan struct{})
+	go func() {
+		// Sales are closed, so no booking can start after this lock is taken
+		promptBookingMu.Lock()
+		promptBookingMu.Unlock()
+		close(queued)
+	}()
+
+	select {
+	case <-queued:
+	case <-done:
+	case <-time.After(timeout):
+		fmt.Println("The last booking did not finish in time; its ticket may not be sent.")
+	}
+}
+
+// listCommand prints one line per booking
+func listCommand(env *environment, fs *flag.FlagSet, args []string) int {
+	conferenceID := fs.String("conference", "", "ID of the conference; may be left out if there is only one")
+	if code, ok := parseArgs(fs, args, 0, 0); !ok {
+		return code
+	}
+	if code, ok := requireDataDir(env, fs.Name()); !ok {
+		return code
+	}
+	conference, err := pickConference(env.registry, *conferenceID)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2
+	}
+
+	bookings, err := conference.Bookings()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tCODE\tNAME\tEMAIL\tTICKETS\tTOTAL\tBOOKED")
+	for _, userData := range bookings {
+		bookedAt := "-"
+		if !userData.BookedAt.IsZero() {
+			bookedAt = userData.BookedAt.Local().Format("2006-01-02 15:04")
+		}
+		fmt.Fprintf(tw, "%v\t%v\t%v %v\t%v\t%v\t%v\t%v\n", userData.ID, booking.FormatConfirmationCode(userData.ConfirmationCode),
+			userData.FirstName, userData.LastName, userData.Email, userData.NumberOfTickets,
+			booking.FormatAmount(userData.Total, userData.Currency), bookedAt)
+	}
+	tw.Flush()
+	return 0
+}
+
+// showCommand prints one booking with its line items
+func showCommand(env *environment, fs *flag.FlagSet, args []string) int {
+	conferenceID := fs.String("conference", "", "ID of the conference; may be left out if there is only one")
+	if code, ok := parseArgs(fs, args, 1, 1); !ok {
+		return code
+	}
+	if code, ok := requireDataDir(env, fs.Name()); !ok {
+		return code
+	}
+	conference, err := pickConference(env.registry, *conferenceID)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2
+	}
+
+	userData, err := findBooking(conference, fs.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	fmt.Printf("%v %v <%v>, %v tickets to %v\n", userData.FirstName, userData.LastName, userData.Email, userData.NumberOfTickets, conference.Name())
+	if !userData.BookedAt.IsZero() {
+		fmt.Printf("Booked at %v\n", userData.BookedAt.Local().Format("2006-01-02 15:04:05"))
+	}
+	printBookingDetails(os.Stdout, conference, userData)
+	return 0
+}
+
+// cancelCommand cancels a booking, or some of its tickets, and emails the notice
+func cancelCommand(env *environment, fs *flag.FlagSet, args []string) int {
+	conferenceID := fs.String("conference", "", "ID of the conference; may be left out if there is only one")
+	tickets := fs.Uint("tickets", 0, "number of tickets to cancel (default all)")
+	tierID := fs.String("tier", "", "ticket type of the cancelled tickets (default the first one)")
+	reason := fs.String("reason", "", "reason recorded in the audit log")
+	if code, ok := parseArgs(fs, args, 1, 1); !ok {
+		return code
+	}
+	if code, ok := requireDataDir(env, fs.Name()); !ok {
+		return code
+	}
+	conference, err := pickConference(env.registry, *conferenceID)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2
+	}
+
+	userData, err := findBooking(conference, fs.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	var items []booking.TicketOrder
+	if *tickets > 0 {
+		if *tierID == "" {
+			*tierID = conference.Tiers()[0].ID
+		}
+		items = []booking.TicketOrder{{Tier: *tierID, Quantity: *tickets}}
+	}
+
+	// Freed tickets may be offered to the waitlist, which sends an email
+	deliveries := env.startDeliveries()
+	cancellation, err := conference.CancelTickets(userData.ID, items, *reason)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	fmt.Printf("Cancelled %v tickets of booking %v. Refund: %v (fee %v)\n", cancellation.NumberOfTickets, cancellation.BookingID,
+		booking.FormatAmount(cancellation.Refund, cancellation.Currency), booking.FormatAmount(cancellation.Fee, cancellation.Currency))
+	if err := sendCancellation(deliveries, conference, cancellation); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// serveCommand runs the HTTP API until the app is interrupted
+func serveCommand(env *environment, fs *flag.FlagSet, args []string) int {
+	addr := env.cfg.Serve
+	if addr == "" {
+		addr = ":8080"
+	}
+	fs.StringVar(&addr, "addr", addr, "address to listen on")
+	if code, ok := parseArgs(fs, args, 0, 0); !ok {
+		return code
+	}
+
+	serve(env.ctx, addr, env.registry, env.startDeliveries(), env.cfg.HoldTTL, env.cfg.ShutdownTimeout)
+	return 0
+}
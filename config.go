@@ -0,0 +1,389 @@
+package main
+
+import (
+	"booking-app/booking"
+	"booking-app/notify"
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// envPrefix starts the name of every environment variable read as a setting,
+// e.g. BOOKING_SMTP_ADDR for the smtp-addr setting
+const envPrefix = "BOOKING_"
+
+// config holds every setting of the application. Each setting has a name,
+// like "smtp-addr", used for its command-line flag, its key in the config
+// file and, as BOOKING_SMTP_ADDR, its environment variable. In increasing
+// order of precedence a setting comes from its default, the config file,
+// the environment and the command line.
+type config struct {
+	ConfigFile string
+	Serve      string
+	EventsFile string
+	DataDir    string
+
+	// The conference offered when no events file is given
+	ConferenceName    string
+	ConferenceTickets uint
+
+	EmailStrictness string
+	EmailBlocklist  string
+	NameRules       booking.NameRules
+	TicketLimits    booking.TicketLimits
+
+	Notify      notify.Config
+	Queue       notify.QueueConfig
+	DeadLetters string
+
+	HoldTTL         time.Duration
+	OfferWindow     time.Duration
+	ShutdownTimeout time.Duration
+
+	// PromptRetries is how often the prompt asks again for an invalid answer
+	PromptRetries int
+
+	// Args are the command-line arguments left after the flags
+	Args []string
+}
+
+// defaultConfig returns the settings used when nothing else is configured
+func defaultConfig() config {
+	return config{
+		ConferenceName:    conferenceName,
+		ConferenceTickets: conferenceTickets,
+		EmailStrictness:   "standard",
+		NameRules:         booking.DefaultNameRules,
+		Notify:            notify.Config{Kind: "stdout", Delay: 10 * time.Second, SMTPAddr: "localhost:25"},
+		Queue:             notify.QueueConfig{Workers: 3, Size: 100, Retry: notify.DefaultRetryPolicy},
+		DeadLetters:       "dead-letters.json",
+		HoldTTL:           booking.DefaultHoldTTL,
+		OfferWindow:       booking.DefaultOfferWindow,
+		ShutdownTimeout:   30 * time.Second,
+		PromptRetries:     3,
+	}
+}
+
+// register defines a flag for every setting, with the current values as defaults
+func (cfg *config) register(fs *flag.FlagSet) {
+	fs.StringVar(&cfg.ConfigFile, "config", cfg.ConfigFile, "file with settings as JSON, or as key = value / key: value lines with optional [sections]")
+	// Passing -serve switches from the interactive prompt to the HTTP JSON API
+	fs.StringVar(&cfg.Serve, "serve", cfg.Serve, "run the HTTP API on the given address (e.g. :8080) instead of the interactive prompt")
+	// Passing -events offers several conferences from one application
+	fs.StringVar(&cfg.EventsFile, "events", cfg.EventsFile, "JSON file listing the conferences (id, name, tickets, starts, ends)")
+	fs.StringVar(&cfg.ConferenceName, "conference-name", cfg.ConferenceName, "name of the conference offered without an events file")
+	fs.UintVar(&cfg.ConferenceTickets, "conference-tickets", cfg.ConferenceTickets, "tickets of the conference offered without an events file")
+	// Passing -data keeps bookings in JSON files so they survive restarts
+	fs.StringVar(&cfg.DataDir, "data", cfg.DataDir, "store bookings in one JSON file per conference in the given directory instead of in memory")
+
+	// Email validation can be tuned and given a list of disposable domains to reject
+	fs.StringVar(&cfg.EmailStrictness, "email-strictness", cfg.EmailStrictness, "email validation level: lenient, standard or strict")
+	fs.StringVar(&cfg.EmailBlocklist, "email-blocklist", cfg.EmailBlocklist, "file with one blocked email domain per line")
+	// Name length limits are counted in characters, not bytes
+	fs.IntVar(&cfg.NameRules.MinLength, "name-min", cfg.NameRules.MinLength, "minimum number of characters in a first or last name")
+	fs.IntVar(&cfg.NameRules.MaxLength, "name-max", cfg.NameRules.MaxLength, "maximum number of characters in a first or last name, 0 for no limit")
+	// Caps against one person buying up the tickets
+	fs.UintVar(&cfg.TicketLimits.PerEmail, "max-per-email", cfg.TicketLimits.PerEmail, "maximum tickets per email address and conference, 0 for no limit")
+	fs.UintVar(&cfg.TicketLimits.PerName, "max-per-name", cfg.TicketLimits.PerName, "maximum tickets per first and last name and conference, 0 for no limit")
+
+	// Ticket confirmations go to stdout unless another notifier is chosen
+	fs.StringVar(&cfg.Notify.Kind, "notifier", cfg.Notify.Kind, "how tickets are sent: stdout, maildir or smtp")
+	fs.DurationVar(&cfg.Notify.Delay, "notify-delay", cfg.Notify.Delay, "simulated delay of the stdout notifier")
+	fs.StringVar(&cfg.Notify.Dir, "maildir", cfg.Notify.Dir, "maildir the maildir notifier writes to")
+	fs.StringVar(&cfg.Notify.SMTPAddr, "smtp-addr", cfg.Notify.SMTPAddr, "SMTP server address (host:port)")
+	fs.StringVar(&cfg.Notify.From, "smtp-from", cfg.Notify.From, "sender address of ticket emails")
+	fs.StringVar(&cfg.Notify.SMTPUsername, "smtp-user", cfg.Notify.SMTPUsername, "SMTP username, if the server requires authentication")
+	fs.StringVar(&cfg.Notify.SMTPPassword, "smtp-password", cfg.Notify.SMTPPassword, "SMTP password")
+
+	// Tickets are sent by a fixed number of workers so bursts cannot overload the mail backend
+	fs.IntVar(&cfg.Queue.Workers, "workers", cfg.Queue.Workers, "number of concurrent ticket deliveries")
+	fs.IntVar(&cfg.Queue.Size, "queue-size", cfg.Queue.Size, "number of tickets that can wait for delivery before booking blocks")
+	// Failed deliveries are retried with backoff and then kept as dead letters
+	fs.IntVar(&cfg.Queue.Retry.MaxAttempts, "max-attempts", cfg.Queue.Retry.MaxAttempts, "number of times a ticket is sent before giving up")
+	fs.DurationVar(&cfg.Queue.Retry.BaseDelay, "retry-delay", cfg.Queue.Retry.BaseDelay, "backoff before the first retry, doubled for every further retry")
+	fs.DurationVar(&cfg.Queue.Retry.MaxDelay, "retry-max-delay", cfg.Queue.Retry.MaxDelay, "maximum backoff between two retries")
+	fs.StringVar(&cfg.DeadLetters, "dead-letters", cfg.DeadLetters, "JSON file keeping tickets that could not be delivered")
+
+	fs.DurationVar(&cfg.HoldTTL, "hold-ttl", cfg.HoldTTL, "longest time the API holds reserved tickets before they go back on sale")
+	fs.DurationVar(&cfg.OfferWindow, "offer-window", cfg.OfferWindow, "how long freed tickets are held for the next person on the waitlist")
+	// On Ctrl-C or SIGTERM queued tickets get this long to be sent before they are kept as dead letters
+	fs.DurationVar(&cfg.ShutdownTimeout, "shutdown-timeout", cfg.ShutdownTimeout, "how long to keep delivering tickets after an interrupt")
+	fs.IntVar(&cfg.PromptRetries, "prompt-retries", cfg.PromptRetries, "invalid answers to one question before the prompt starts over, 0 for no limit")
+}
+
+// loadConfig builds the configuration from the command-line arguments, the
+// environment (looked up through lookupEnv) and the config file named by
+// -config or BOOKING_CONFIG. Every invalid setting is reported in one error.
+// It returns flag.ErrHelp if the arguments asked for the usage message.
+func loadConfig(args []string, lookupEnv func(string) (string, bool)) (config, error) {
+	cfg := defaultConfig()
+	fs := flag.NewFlagSet(filepath.Base(os.Args[0]), flag.ContinueOnError)
+	cfg.register(fs)
+	fs.Usage = func() {
+		printUsage(fs.Output())
+		fmt.Fprintln(fs.Output(), "\nFlags (before the command; also read from BOOKING_* variables and -config):")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return config{}, err
+	}
+	cfg.Args = fs.Args()
+
+	fromFlags := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) {
+		fromFlags[f.Name] = true
+	})
+	if configFile, ok := lookupEnv(envName("config")); ok && !fromFlags["config"] {
+		cfg.ConfigFile = configFile
+	}
+
+	var problems []string
+	fileSettings := map[string]string{}
+	if cfg.ConfigFile != "" {
+		var err error
+		fileSettings, err = readConfigFile(cfg.ConfigFile)
+		if err != nil {
+			return config{}, err
+		}
+	}
+	for _, name := range sortedKeys(fileSettings) {
+		if fs.Lookup(name) == nil || name == "config" {
+			problems = append(problems, fmt.Sprintf("%v: unknown setting %q", cfg.ConfigFile, name))
+		}
+	}
+
+	// Flags were applied by Parse; fill in the rest from the environment or the file
+	fs.VisitAll(func(f *flag.Flag) {
+		if fromFlags[f.Name] || f.Name == "config" {
+			return
+		}
+		source := "environment variable " + envName(f.Name)
+		value, ok := lookupEnv(envName(f.Name))
+		if !ok {
+			source = cfg.ConfigFile
+			value, ok = fileSettings[f.Name]
+		}
+		if !ok {
+			return
+		}
+		if err := f.Value.Set(value); err != nil {
+			problems = append(problems, fmt.Sprintf("%v: invalid value %q for %v", source, value, f.Name))
+		}
+	})
+
+	problems = append(problems, cfg.validate()...)
+	if len(problems) > 0 {
+		return config{}, fmt.Errorf("invalid configuration:\n  %v", strings.Join(problems, "\n  "))
+	}
+	return cfg, nil
+}
+
+// validate checks that the settings make sense together and returns a
+// description of every problem found
+func (cfg config) validate() []string {
+	var problems []string
+	check := func(ok bool, format string, args ...interface{}) {
+		if !ok {
+			problems = append(problems, fmt.Sprintf(format, args...))
+		}
+	}
+
+	check(cfg.ConferenceName != "", "conference-name must not be empty")
+	check(cfg.ConferenceTickets > 0, "conference-tickets must be at least 1")
+
+	_, err := booking.ParseEmailStrictness(cfg.EmailStrictness)
+	check(err == nil, "email-strictness must be lenient, standard or strict, not %q", cfg.EmailStrictness)
+	check(cfg.NameRules.MinLength >= 1, "name-min must be at least 1")
+	check(cfg.NameRules.MaxLength == 0 || cfg.NameRules.MaxLength >= cfg.NameRules.MinLength, "name-max must be 0 or at least name-min (%v)", cfg.NameRules.MinLength)
+
+	switch cfg.Notify.Kind {
+	case "stdout":
+		check(cfg.Notify.Delay >= 0, "notify-delay must not be negative")
+	case "maildir":
+		check(cfg.Notify.Dir != "", "maildir must be set for the maildir notifier")
+	case "smtp":
+		_, _, err := net.SplitHostPort(cfg.Notify.SMTPAddr)
+		check(err == nil, "smtp-addr must be host:port, not %q", cfg.Notify.SMTPAddr)
+		check(cfg.Notify.From != "", "smtp-from must be set for the smtp notifier")
+	default:
+		problems = append(problems, fmt.Sprintf("notifier must be stdout, maildir or smtp, not %q", cfg.Notify.Kind))
+	}
+
+	check(cfg.Queue.Workers >= 1, "workers must be at least 1")
+	check(cfg.Queue.Size >= 0, "queue-size must not be negative")
+	check(cfg.Queue.Retry.MaxAttempts >= 1, "max-attempts must be at least 1")
+	check(cfg.Queue.Retry.BaseDelay >= 0, "retry-delay must not be negative")
+	check(cfg.Queue.Retry.MaxDelay >= cfg.Queue.Retry.BaseDelay, "retry-max-delay must be at least retry-delay (%v)", cfg.Queue.Retry.BaseDelay)
+	check(cfg.DeadLetters != "", "dead-letters must not be empty")
+
+	check(cfg.HoldTTL > 0, "hold-ttl must be positive")
+	check(cfg.OfferWindow > 0, "offer-window must be positive")
+	check(cfg.ShutdownTimeout >= 0, "shutdown-timeout must not be negative")
+	check(cfg.PromptRetries >= 0, "prompt-retries must not be negative")
+	return problems
+}
+
+// envName returns the environment variable of a setting, e.g. BOOKING_SMTP_ADDR
+func envName(setting string) string {
+	return envPrefix + strings.ToUpper(strings.ReplaceAll(setting, "-", "_"))
+}
+
+// readConfigFile reads the settings of a config file by name.
+// Files ending in .json hold a JSON object; anything else is read by parseConfigLines.
+// Nested objects and sections are joined to the setting name with '-', so
+// {"smtp": {"addr": "..."}} and "[smtp]" followed by "addr = ..." both set smtp-addr.
+func readConfigFile(path string) (map[string]string, error) {
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		var object map[string]interface{}
+		if err := json.Unmarshal(data, &object); err != nil {
+			return nil, fmt.Errorf("%v: %w", path, err)
+		}
+		settings := make(map[string]string)
+		if err := flattenJSON(settings, "", object); err != nil {
+			return nil, fmt.Errorf("%v: %w", path, err)
+		}
+		return settings, nil
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	settings, err := parseConfigLines(file)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %w", path, err)
+	}
+	return settings, nil
+}
+
+// flattenJSON adds the scalar values of object to settings, joining nested keys with '-'
+func flattenJSON(settings map[string]string, prefix string, object map[string]interface{}) error {
+	for key, value := range object {
+		name := prefix + settingName(key)
+		switch value := value.(type) {
+		case map[string]interface{}:
+			if err := flattenJSON(settings, name+"-", value); err != nil {
+				return err
+			}
+		case string:
+			settings[name] = value
+		case float64:
+			settings[name] = strconv.FormatFloat(value, 'f', -1, 64)
+		case bool:
+			settings[name] = strconv.FormatBool(value)
+		case nil:
+		default:
+			return fmt.Errorf("setting %q must be a string, number or boolean", name)
+		}
+	}
+	return nil
+}
+
+// parseConfigLines reads the simple subset of YAML and TOML that flat
+// settings need: "key: value" or "key = value" lines, '#' comments, quoted
+// values, TOML "[section]" headers and YAML keys nested by indentation.
+func parseConfigLines(r io.Reader) (map[string]string, error) {
+	type section struct {
+		indent int
+		prefix string
+	}
+	var sections []section
+	settings := make(map[string]string)
+
+	scanner := bufio.NewScanner(r)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimRight(scanner.Text(), " \t\r")
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") || trimmed == "---" {
+			continue
+		}
+		indent := len(line) - len(strings.TrimLeft(line, " \t"))
+
+		// A TOML section applies until the next one
+		if strings.HasPrefix(trimmed, "[") && strings.HasSuffix(trimmed, "]") {
+			sections = []section{{indent: -1, prefix: settingName(trimmed[1:len(trimmed)-1]) + "-"}}
+			continue
+		}
+		// A YAML section applies to the lines indented below it
+		for len(sections) > 0 && sections[len(sections)-1].indent >= indent {
+			sections = sections[:len(sections)-1]
+		}
+		prefix := ""
+		if len(sections) > 0 {
+			prefix = sections[len(sections)-1].prefix
+		}
+
+		separator := strings.IndexAny(trimmed, ":=")
+		if separator <= 0 {
+			return nil, fmt.Errorf("line %v: expected key: value or key = value", lineNumber)
+		}
+		name := prefix + settingName(trimmed[:separator])
+		value := strings.TrimSpace(trimmed[separator+1:])
+		if value == "" {
+			sections = append(sections, section{indent: indent, prefix: name + "-"})
+			continue
+		}
+
+		unquoted, err := unquoteValue(value)
+		if err != nil {
+			return nil, fmt.Errorf("line %v: %w", lineNumber, err)
+		}
+		settings[name] = unquoted
+	}
+	return settings, scanner.Err()
+}
+
+// unquoteValue removes the quotes around a value, or a trailing " # comment" from an unquoted one
+func unquoteValue(value string) (string, error) {
+	switch value[0] {
+	case '"':
+		end := strings.LastIndex(value, `"`)
+		if end == 0 {
+			return "", fmt.Errorf("unterminated string %v", value)
+		}
+		return strconv.Unquote(value[:end+1])
+	case '\'':
+		end := strings.LastIndex(value, "'")
+		if end == 0 {
+			return "", fmt.Errorf("unterminated string %v", value)
+		}
+		return value[1:end], nil
+	}
+	if comment := strings.Index(value, " #"); comment >= 0 {
+		value = strings.TrimSpace(value[:comment])
+	}
+	return value, nil
+}
+
+// settingName converts a key as written in a config file to a setting name:
+// "SMTP_Addr", "smtp.addr" and "smtp-addr" all become "smtp-addr"
+func settingName(key string) string {
+	key = strings.ToLower(strings.TrimSpace(key))
+	return strings.NewReplacer("_", "-", ".", "-", " ", "-").Replace(key)
+}
+
+// sortedKeys returns the keys of settings in alphabetical order
+func sortedKeys(settings map[string]string) []string {
+	keys := make([]string, 0, len(settings))
+	for key := range settings {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
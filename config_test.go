@@ -0,0 +1,226 @@
+package main
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// noEnv is a lookupEnv for an empty environment
+func noEnv(string) (string, bool) {
+	return "", false
+}
+
+// writeConfigFile writes contents to a file called name in a temporary directory
+func writeConfigFile(t *testing.T, name, contents string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := ioutil.WriteFile(path, []byte(contents), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestParseConfigLines(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  map[string]string
+	}{
+		{"key value lines", "serve: :8080\nworkers = 5\n",
+			map[string]string{"serve": ":8080", "workers": "5"}},
+		{"comments and blank lines", "# settings\n---\n\nworkers: 5 # enough\n",
+			map[string]string{"workers": "5"}},
+		{"key spellings", "SMTP_Addr: a:25\nsmtp.from: b@example.com\n",
+			map[string]string{"smtp-addr": "a:25", "smtp-from": "b@example.com"}},
+		{"nested keys", "smtp:\n  addr: mail:25\n  from: tickets@example.com\nworkers: 2\n",
+			map[string]string{"smtp-addr": "mail:25", "smtp-from": "tickets@example.com", "workers": "2"}},
+		{"deeply nested keys", "retry:\n  max:\n    delay: 1m\n  delay: 1s\n",
+			map[string]string{"retry-max-delay": "1m", "retry-delay": "1s"}},
+		{"sections", "workers = 1\n[smtp]\naddr = mail:25\n[retry]\ndelay = 1s\n",
+			map[string]string{"workers": "1", "smtp-addr": "mail:25", "retry-delay": "1s"}},
+		{"quoted values keep #", "smtp-password: \"p#ss # word\" # comment\nsmtp-from = 'a#b@example.com'\n",
+			map[string]string{"smtp-password": "p#ss # word", "smtp-from": "a#b@example.com"}},
+		{"unknown keys are kept", "colour: blue\n",
+			map[string]string{"colour": "blue"}},
+	}
+	for _, test := range tests {
+		got, err := parseConfigLines(strings.NewReader(test.input))
+		if err != nil {
+			t.Errorf("%v: %v", test.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%v: got %v, want %v", test.name, got, test.want)
+		}
+	}
+
+	for _, input := range []string{"no separator\n", ": value\n", "serve: \"unterminated\n"} {
+		if _, err := parseConfigLines(strings.NewReader(input)); err == nil {
+			t.Errorf("%q parsed without an error", input)
+		}
+	}
+}
+
+func TestUnquoteValue(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"plain", "plain"},
+		{"plain # comment", "plain"},
+		{"no#comment", "no#comment"},
+		{`"double # quoted"`, "double # quoted"},
+		{`"escaped \"quote\""`, `escaped "quote"`},
+		{`"tab\t" # comment`, "tab\t"},
+		{`'single # quoted'`, "single # quoted"},
+		{`'no \t escapes'`, `no \t escapes`},
+	}
+	for _, test := range tests {
+		got, err := unquoteValue(test.value)
+		if err != nil || got != test.want {
+			t.Errorf("unquoteValue(%q) = %q, %v, want %q", test.value, got, err, test.want)
+		}
+	}
+
+	for _, value := range []string{`"open`, `'open`} {
+		if _, err := unquoteValue(value); err == nil {
+			t.Errorf("unquoteValue(%q) succeeded", value)
+		}
+	}
+}
+
+func TestFlattenJSON(t *testing.T) {
+	object := map[string]interface{}{
+		"serve":   ":8080",
+		"workers": float64(5),
+		"SMTP": map[string]interface{}{
+			"addr": "mail:25",
+			"retry": map[string]interface{}{
+				"max_delay": "1m",
+			},
+		},
+		"notify_delay": float64(1.5),
+		"strict":       true,
+		"unset":        nil,
+	}
+	settings := make(map[string]string)
+	if err := flattenJSON(settings, "", object); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"serve":                ":8080",
+		"workers":              "5",
+		"smtp-addr":            "mail:25",
+		"smtp-retry-max-delay": "1m",
+		"notify-delay":         "1.5",
+		"strict":               "true",
+	}
+	if !reflect.DeepEqual(settings, want) {
+		t.Errorf("got %v, want %v", settings, want)
+	}
+
+	list := map[string]interface{}{"workers": []interface{}{float64(1)}}
+	if err := flattenJSON(make(map[string]string), "", list); err == nil {
+		t.Error("a list was accepted as a setting")
+	}
+}
+
+func TestLoadConfigPrecedence(t *testing.T) {
+	file := writeConfigFile(t, "booking.yaml", "workers: 4\nqueue-size: 40\nhold-ttl: 4m\n")
+	env := map[string]string{
+		"BOOKING_QUEUE_SIZE": "50",
+		"BOOKING_HOLD_TTL":   "5m",
+	}
+	lookupEnv := func(name string) (string, bool) {
+		value, ok := env[name]
+		return value, ok
+	}
+
+	cfg, err := loadConfig([]string{"-config", file, "-hold-ttl", "6m", "list"}, lookupEnv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := cfg.HoldTTL, 6*time.Minute; got != want {
+		t.Errorf("flag over environment: hold-ttl = %v, want %v", got, want)
+	}
+	if got, want := cfg.Queue.Size, 50; got != want {
+		t.Errorf("environment over file: queue-size = %v, want %v", got, want)
+	}
+	if got, want := cfg.Queue.Workers, 4; got != want {
+		t.Errorf("file over default: workers = %v, want %v", got, want)
+	}
+	if got, want := cfg.Queue.Retry.MaxAttempts, defaultConfig().Queue.Retry.MaxAttempts; got != want {
+		t.Errorf("default: max-attempts = %v, want %v", got, want)
+	}
+	if !reflect.DeepEqual(cfg.Args, []string{"list"}) {
+		t.Errorf("args = %v, want [list]", cfg.Args)
+	}
+}
+
+func TestLoadConfigFileFromEnvironment(t *testing.T) {
+	fromEnv := writeConfigFile(t, "env.conf", "[smtp]\nfrom = env@example.com\n")
+	fromFlag := writeConfigFile(t, "flag.json", `{"smtp": {"from": "flag@example.com"}}`)
+	lookupEnv := func(name string) (string, bool) {
+		if name == "BOOKING_CONFIG" {
+			return fromEnv, true
+		}
+		return "", false
+	}
+
+	cfg, err := loadConfig(nil, lookupEnv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Notify.From != "env@example.com" {
+		t.Errorf("BOOKING_CONFIG: smtp-from = %q, want env@example.com", cfg.Notify.From)
+	}
+
+	cfg, err = loadConfig([]string{"-config", fromFlag}, lookupEnv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Notify.From != "flag@example.com" {
+		t.Errorf("-config over BOOKING_CONFIG: smtp-from = %q, want flag@example.com", cfg.Notify.From)
+	}
+}
+
+func TestLoadConfigReportsEveryProblem(t *testing.T) {
+	file := writeConfigFile(t, "booking.toml", "colour = blue\nconfig = other.toml\n[smtp]\nport = 25\n")
+	lookupEnv := func(name string) (string, bool) {
+		if name == "BOOKING_WORKERS" {
+			return "many", true
+		}
+		return "", false
+	}
+
+	_, err := loadConfig([]string{"-config", file, "-max-attempts", "0"}, lookupEnv)
+	if err == nil {
+		t.Fatal("invalid configuration was accepted")
+	}
+	for _, want := range []string{
+		`unknown setting "colour"`,
+		`unknown setting "config"`,
+		`unknown setting "smtp-port"`,
+		`environment variable BOOKING_WORKERS: invalid value "many" for workers`,
+		"max-attempts must be at least 1",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not mention %q", err, want)
+		}
+	}
+}
+
+func TestLoadConfigDefaults(t *testing.T) {
+	cfg, err := loadConfig(nil, noEnv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.Args = nil
+	if want := defaultConfig(); !reflect.DeepEqual(cfg, want) {
+		t.Errorf("got %+v, want the defaults %+v", cfg, want)
+	}
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"booking-app/notify"
+	"context"
+	"fmt"
+)
+
+// deadLettersCommand lets an operator inspect and resend tickets that could
+// not be delivered. It returns the exit code of the program.
+func deadLettersCommand(ctx context.Context, args []string, deadLetters *notify.DeadLetterFile, notifier notify.Notifier, retry notify.RetryPolicy) int {
+	if len(args) != 1 {
+		fmt.Println("Usage: booking-app [flags] dead-letters list|redrive")
+		return 2
+	}
+
+	switch args[0] {
+	case "list":
+		list, err := deadLetters.List()
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return 1
+		}
+		for i, deadLetter := range list {
+			fmt.Printf("%v. %v | %q | %v attempts | failed %v | %v\n", i+1, deadLetter.Message.To, deadLetter.Message.Subject,
+				deadLetter.Attempts, deadLetter.FailedAt.Format("2006-01-02 15:04:05"), deadLetter.LastError)
+		}
+		fmt.Printf("%v undelivered tickets\n", len(list))
+		return 0
+	case "redrive":
+		delivered, err := deadLetters.Redrive(ctx, notifier, retry)
+		fmt.Printf("%v tickets delivered\n", delivered)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return 1
+		}
+		list, err := deadLetters.List()
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return 1
+		}
+		if len(list) > 0 {
+			fmt.Printf("%v tickets are still undeliverable\n", len(list))
+			return 1
+		}
+		return 0
+	}
+
+	fmt.Printf("Unknown dead-letters command %q\n", args[0])
+	return 2
+}
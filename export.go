@@ -0,0 +1,66 @@
+package main
+
+import (
+	"booking-app/booking"
+	"booking-app/internal/atomicfile"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// exportCommand writes the bookings of a conference as CSV, JSON or NDJSON
+// to standard output or a file. It returns the exit code of the program.
+func exportCommand(env *environment, fs *flag.FlagSet, args []string) int {
+	conferenceID := fs.String("conference", "", "ID of the conference to export; may be left out if there is only one")
+	format := fs.String("format", "csv", "output format: csv, json or ndjson")
+	fields := fs.String("fields", "", "comma-separated fields to write, in order (default all: "+strings.Join(booking.ExportFields, ",")+")")
+	output := fs.String("o", "", "file to write to instead of standard output")
+	var opts booking.ExportOptions
+	fs.StringVar(&opts.EmailDomain, "domain", "", "only export bookings with an email address on this domain")
+	fs.UintVar(&opts.MinTickets, "min-tickets", 0, "only export bookings with at least this many tickets")
+	fs.UintVar(&opts.MaxTickets, "max-tickets", 0, "only export bookings with at most this many tickets, 0 for no limit")
+	if code, ok := parseArgs(fs, args, 0, 0); !ok {
+		return code
+	}
+	if code, ok := requireDataDir(env, fs.Name()); !ok {
+		return code
+	}
+
+	var err error
+	opts.Format, err = booking.ParseExportFormat(*format)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2
+	}
+	if *fields != "" {
+		for _, field := range strings.Split(*fields, ",") {
+			opts.Fields = append(opts.Fields, strings.TrimSpace(field))
+		}
+	}
+	conference, err := pickConference(env.registry, *conferenceID)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2
+	}
+
+	if err := exportBookings(conference, *output, opts); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// exportBookings writes the export to the file at path, or to standard output
+// if path is empty. Like the data files, the file is written under a
+// temporary name and renamed, so it is only replaced by a complete export.
+func exportBookings(conference *booking.Conference, path string, opts booking.ExportOptions) error {
+	if path == "" {
+		return conference.Export(os.Stdout, opts)
+	}
+
+	return atomicfile.Write(path, func(w io.Writer) error {
+		return conference.Export(w, opts)
+	})
+}
@@ -1,18 +1,141 @@
 package main
 
-import "strings"
+import (
+	"booking-app/booking"
+	"errors"
+	"fmt"
+)
+
+// emailRules configures how strictly email addresses are checked.
+// main sets it from the command-line flags before any input is validated.
+var emailRules = booking.EmailRules{}
+
+// nameRules configures which first and last names are accepted
+var nameRules = booking.DefaultNameRules
 
 // validateUserInput contains the core validation logic for booking data.
-// It checks name lengths, email formatting, and ticket availability.
-func validateUserInput(firstName string, lastName string, email string, userTickets uint) (bool, bool, bool) {
-	// Rule: Names must be at least 2 characters long
-	isValidName := len(firstName) >= 2 && len(lastName) >= 2
+// It checks names, email formatting, ticket availability per tier, the
+// tickets already booked by the same person and the optional promo code
+// for the chosen conference.
+// All broken rules are reported together in a *booking.ValidationError.
+func validateUserInput(conference *booking.Conference, firstName string, lastName string, email string, orders []booking.TicketOrder, promoCode string) error {
+	var validationErr booking.ValidationError
+
+	// Rule: Names must be made of letters and have a sensible length in characters
+	var nameErr *booking.NameError
+	if errors.As(booking.ValidateName(firstName, nameRules), &nameErr) {
+		validationErr.Add("firstName", nameErr.Code, fmt.Sprintf("First name %v.", nameErr.Message), firstName)
+	}
+	if errors.As(booking.ValidateName(lastName, nameRules), &nameErr) {
+		validationErr.Add("lastName", nameErr.Code, fmt.Sprintf("Last name %v.", nameErr.Message), lastName)
+	}
+
+	// Rule: Email must be a well-formed address on a domain that is not blocked
+	if err := booking.ValidateEmail(email, emailRules); errors.Is(err, booking.ErrDisposableEmail) {
+		validationErr.Add("email", booking.CodeBlockedEmail, "Email addresses from this domain are not accepted.", email)
+	} else if err != nil {
+		validationErr.Add("email", booking.CodeInvalidEmail, fmt.Sprintf("Invalid email address: %v.", err), email)
+	}
+
+	// Rule: Must book at least 1 ticket and not exceed the availability of any tier
+	var userTickets uint
+	for _, order := range orders {
+		userTickets += order.Quantity
+		tier, err := conference.Tier(order.Tier)
+		if err != nil {
+			validationErr.Add("tier", booking.CodeUnknownTier, fmt.Sprintf("Unknown ticket type %q.", order.Tier), order.Tier)
+			continue
+		}
+		if remainingTickets := conference.RemainingInTier(tier.ID); order.Quantity > remainingTickets {
+			validationErr.Add("numberOfTickets", booking.CodeNotEnoughTickets, fmt.Sprintf("Invalid number of %v tickets. Only %v remaining.", tier.Name, remainingTickets), order.Quantity)
+		}
+	}
+	if userTickets == 0 {
+		validationErr.Add("numberOfTickets", booking.CodeInvalidTicket, "Invalid number of tickets. Book at least 1 ticket.", userTickets)
+	}
+
+	// Rule: Nobody may book more tickets in total than the limits allow
+	if err := checkTicketLimits(&validationErr, conference, firstName, lastName, email, userTickets); err != nil {
+		return err
+	}
 
-	// Rule: Email must contain '@'
-	isValidEmail := strings.Contains(email, "@")
+	// Rule: A promo code must exist, be valid now and apply to the ordered tickets
+	if promoCode != "" && len(validationErr.Errors) == 0 {
+		if err := conference.CheckPromoCode(promoCode, email, orders); err != nil {
+			validationErr.Add("promoCode", booking.CodeInvalidPromo, fmt.Sprintf("Promo code %q cannot be used: %v.", promoCode, promoMessage(err)), promoCode)
+		}
+	}
 
-	// Rule: Must book at least 1 ticket and not exceed availability
-	isValidTicketNumber := userTickets > 0 && userTickets <= remainingTickets
+	return validationErr.Err()
+}
+
+// validatePersonalDetails checks the names, the email address and the ticket
+// limits, but not availability, for requests whose tickets are not for sale
+// right now: someone joining the waitlist, or confirming tickets that are
+// already held for them.
+func validatePersonalDetails(conference *booking.Conference, firstName string, lastName string, email string, userTickets uint) error {
+	err := validateUserInput(conference, firstName, lastName, email, nil, "")
+
+	var validationErr *booking.ValidationError
+	if err != nil && !errors.As(err, &validationErr) {
+		return err
+	}
+	var personalErr booking.ValidationError
+	if validationErr != nil {
+		for _, fieldError := range validationErr.Errors {
+			if fieldError.Field != "numberOfTickets" {
+				personalErr.Errors = append(personalErr.Errors, fieldError)
+			}
+		}
+	}
+	if err := checkTicketLimits(&personalErr, conference, firstName, lastName, email, userTickets); err != nil {
+		return err
+	}
+	return personalErr.Err()
+}
+
+// checkTicketLimits adds a field error for every ticket limit that booking
+// userTickets more would exceed. Emails are compared in normalized form, so
+// "Ann+2@Example.com" counts against the same limit as "ann@example.com".
+// It only returns an error if the existing bookings could not be read.
+// The conference checks the limits again while booking; this check is
+// there for the specific messages.
+func checkTicketLimits(validationErr *booking.ValidationError, conference *booking.Conference, firstName string, lastName string, email string, userTickets uint) error {
+	ticketLimits := conference.TicketLimits()
+	if ticketLimits.PerEmail > 0 {
+		booked, err := conference.TicketsBookedBy(email)
+		if err != nil {
+			return err
+		}
+		if booked+userTickets > ticketLimits.PerEmail {
+			validationErr.Add("numberOfTickets", booking.CodeTicketLimit, fmt.Sprintf("At most %v tickets per email address; %v already booked with %v.", ticketLimits.PerEmail, booked, email), userTickets)
+		}
+	}
+	if ticketLimits.PerName > 0 {
+		booked, err := conference.TicketsBookedByName(firstName, lastName)
+		if err != nil {
+			return err
+		}
+		if booked+userTickets > ticketLimits.PerName {
+			validationErr.Add("numberOfTickets", booking.CodeTicketLimit, fmt.Sprintf("At most %v tickets per person; %v already booked for %v %v.", ticketLimits.PerName, booked, booking.NormalizeName(firstName), booking.NormalizeName(lastName)), userTickets)
+		}
+	}
+	return nil
+}
 
-	return isValidName, isValidEmail, isValidTicketNumber
+// promoMessage explains why a promo code was rejected
+func promoMessage(err error) string {
+	switch {
+	case errors.Is(err, booking.ErrPromoUnknown):
+		return "it does not exist"
+	case errors.Is(err, booking.ErrPromoExpired):
+		return "it has expired"
+	case errors.Is(err, booking.ErrPromoUsedUp):
+		return "it has been used up"
+	case errors.Is(err, booking.ErrPromoEmailLimit):
+		return "it was already redeemed with this email address"
+	case errors.Is(err, booking.ErrPromoNotApplicable):
+		return "it does not apply to these tickets"
+	}
+	return err.Error()
 }
@@ -0,0 +1,384 @@
+package main
+
+import (
+	"booking-app/booking"
+	"booking-app/notify"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Statuses of an imported row
+const (
+	importBooked    = "booked"
+	importWouldBook = "would-book"
+	importRejected  = "rejected"
+	importSkipped   = "skipped"
+)
+
+// importColumns maps the accepted header names, lower-cased and without
+// spaces, '_' and '-', to the columns of an import row
+var importColumns = map[string]string{
+	"firstname":       "firstName",
+	"lastname":        "lastName",
+	"email":           "email",
+	"emailaddress":    "email",
+	"tickets":         "tickets",
+	"numberoftickets": "tickets",
+	"tier":            "tier",
+	"tickettype":      "tier",
+	"promocode":       "promoCode",
+	"promo":           "promoCode",
+}
+
+// importOrder is the column order of files without a header row
+var importOrder = []string{"firstName", "lastName", "email", "tickets", "tier", "promoCode"}
+
+// importRow is one booking request read from a CSV file
+type importRow struct {
+	Line      int
+	FirstName string
+	LastName  string
+	Email     string
+	Tickets   uint
+	Tier      string
+	PromoCode string
+	// Err is set if the row itself could not be read
+	Err error
+}
+
+// importResult reports what happened to one row of an import
+type importResult struct {
+	Row              int      `json:"row"`
+	Status           string   `json:"status"`
+	FirstName        string   `json:"firstName"`
+	LastName         string   `json:"lastName"`
+	Email            string   `json:"email"`
+	NumberOfTickets  uint     `json:"numberOfTickets"`
+	BookingID        uint     `json:"bookingId,omitempty"`
+	ConfirmationCode string   `json:"confirmationCode,omitempty"`
+	Reasons          []string `json:"reasons,omitempty"`
+}
+
+// importOptions controls how rows are booked
+type importOptions struct {
+	// Tier is booked by rows without a tier column; empty means the first tier
+	Tier string
+	// DryRun checks every row without booking anything
+	DryRun bool
+	// SendTickets queues a ticket email for every booked row
+	SendTickets bool
+}
+
+// importCommand books the rows of a CSV file of first name, last name, email
+// and tickets, and prints one result line per row. It returns the exit code
+// of the program: 1 if any row was not booked.
+func importCommand(env *environment, fs *flag.FlagSet, args []string) int {
+	conferenceID := fs.String("conference", "", "ID of the conference to book; may be left out if there is only one")
+	var opts importOptions
+	fs.StringVar(&opts.Tier, "tier", "", "ticket type for rows without a tier column (default the first one)")
+	fs.BoolVar(&opts.DryRun, "dry-run", false, "check every row and report what would be booked, without booking")
+	fs.BoolVar(&opts.SendTickets, "send-tickets", true, "email the tickets of booked rows")
+	asJSON := fs.Bool("json", false, "print the report as JSON")
+	if code, ok := parseArgs(fs, args, 1, 1); !ok {
+		return code
+	}
+	if !opts.DryRun {
+		if code, ok := requireDataDir(env, fs.Name()); !ok {
+			return code
+		}
+	}
+
+	conference, err := pickConference(env.registry, *conferenceID)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2
+	}
+
+	in := os.Stdin
+	if path := fs.Arg(0); path != "-" {
+		in, err = os.Open(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		defer in.Close()
+	}
+	rows, err := readImportRows(in)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	// Starting deliveries also offers freed tickets to the waitlist, which a
+	// dry run must not do
+	var deliveries *notify.Queue
+	if !opts.DryRun && opts.SendTickets {
+		deliveries = env.startDeliveries()
+	}
+	results, err := importBookings(conference, rows, opts, deliveries)
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(results); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+	} else {
+		printImportReport(os.Stdout, results, opts.DryRun)
+	}
+	if err != nil {
+		// The rows before the failure are reported above
+		fmt.Fprintf(os.Stderr, "Import stopped at row %v: %v\n", rows[len(results)].Line, err)
+		return 1
+	}
+
+	for _, result := range results {
+		if result.Status != importBooked && result.Status != importWouldBook {
+			return 1
+		}
+	}
+	return 0
+}
+
+// readImportRows reads the booking requests from CSV. The first row is taken
+// as a header if its tickets column is not a number; otherwise the columns
+// are first name, last name, email, tickets, tier and promo code, of which
+// the last two may be left out. Rows that cannot be read, for example
+// because of a missing column, are returned with Err set so they show up in
+// the report; only a broken file fails as a whole.
+func readImportRows(in io.Reader) ([]importRow, error) {
+	reader := csv.NewReader(in)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	columns := importOrder
+	var rows []importRow
+	for first := true; ; first = false {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		line, _ := reader.FieldPos(0)
+
+		if first && isImportHeader(record) {
+			columns, err = importHeader(record)
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+		rows = append(rows, parseImportRow(line, record, columns))
+	}
+}
+
+// isImportHeader reports whether the first record names the columns
+func isImportHeader(record []string) bool {
+	if len(record) < 4 {
+		return false
+	}
+	_, err := strconv.ParseUint(strings.TrimSpace(record[3]), 10, 0)
+	return err != nil
+}
+
+// importHeader maps the header names to columns; unknown names are ignored
+func importHeader(record []string) ([]string, error) {
+	columns := make([]string, len(record))
+	seen := make(map[string]bool)
+	for i, name := range record {
+		key := strings.ToLower(strings.NewReplacer(" ", "", "_", "", "-", "").Replace(name))
+		columns[i] = importColumns[key]
+		seen[columns[i]] = true
+	}
+	for _, required := range importOrder[:4] {
+		if !seen[required] {
+			return nil, fmt.Errorf("the header row has no %v column", required)
+		}
+	}
+	return columns, nil
+}
+
+// parseImportRow reads the fields of one record into a row
+func parseImportRow(line int, record []string, columns []string) importRow {
+	row := importRow{Line: line}
+	if len(record) < 4 || len(record) > len(columns) {
+		// The columns that are there still identify the row in the report
+		row.Err = fmt.Errorf("Expected %v columns, found %v.", columnRange(len(columns)), len(record))
+	}
+
+	for i, field := range record {
+		if i >= len(columns) {
+			break
+		}
+		field = strings.TrimSpace(field)
+		switch columns[i] {
+		case "firstName":
+			row.FirstName = field
+		case "lastName":
+			row.LastName = field
+		case "email":
+			row.Email = field
+		case "tickets":
+			tickets, err := strconv.ParseUint(field, 10, 0)
+			if err != nil && row.Err == nil {
+				row.Err = fmt.Errorf("Invalid number of tickets %q.", field)
+			}
+			row.Tickets = uint(tickets)
+		case "tier":
+			row.Tier = field
+		case "promoCode":
+			row.PromoCode = field
+		}
+	}
+	return row
+}
+
+// columnRange describes how many columns a row may have
+func columnRange(columns int) string {
+	if columns == 4 {
+		return "4"
+	}
+	return fmt.Sprintf("4 to %v", columns)
+}
+
+// importBookings runs every row through validateUserInput and books it,
+// unless it is a dry run. Rows asking for more tickets than are left are
+// skipped. A dry run books into a copy of the conference instead, so earlier
+// rows count against the tickets left, the ticket limits and the promo code
+// limits exactly like in a real import.
+// deliveries is only used to send tickets and may be nil for a dry run or
+// without SendTickets.
+// It stops at the first error that is not about the row itself, such as a
+// failing store, and returns the results so far together with the error.
+func importBookings(conference *booking.Conference, rows []importRow, opts importOptions, deliveries *notify.Queue) ([]importResult, error) {
+	if opts.Tier == "" {
+		opts.Tier = conference.Tiers()[0].ID
+	}
+	if opts.DryRun {
+		var err error
+		if conference, err = conference.Copy(); err != nil {
+			return nil, err
+		}
+	}
+
+	results := make([]importResult, 0, len(rows))
+	for _, row := range rows {
+		result := importResult{Row: row.Line, FirstName: row.FirstName, LastName: row.LastName, Email: row.Email, NumberOfTickets: row.Tickets}
+		if row.Err != nil {
+			result.Status = importRejected
+			result.Reasons = []string{row.Err.Error()}
+			results = append(results, result)
+			continue
+		}
+
+		tier := row.Tier
+		if tier == "" {
+			tier = opts.Tier
+		}
+		if reason := soldOutReason(conference, tier, row.Tickets); reason != "" {
+			result.Status = importSkipped
+			result.Reasons = []string{reason}
+			results = append(results, result)
+			continue
+		}
+
+		orders := []booking.TicketOrder{{Tier: tier, Quantity: row.Tickets}}
+		err := validateUserInput(conference, row.FirstName, row.LastName, row.Email, orders, row.PromoCode)
+		var validationErr *booking.ValidationError
+		if errors.As(err, &validationErr) {
+			result.Status = importRejected
+			for _, fieldError := range validationErr.Errors {
+				result.Reasons = append(result.Reasons, fieldError.Message)
+			}
+			results = append(results, result)
+			continue
+		}
+		if err != nil {
+			return results, err
+		}
+
+		order := booking.Order{FirstName: row.FirstName, LastName: row.LastName, Email: row.Email, Items: orders, PromoCode: row.PromoCode}
+		userData, err := conference.BookOrder(order)
+		switch {
+		case errors.Is(err, booking.ErrNotEnoughTickets):
+			result.Status = importSkipped
+			result.Reasons = []string{"Sold out."}
+		case errors.Is(err, booking.ErrTicketLimit):
+			result.Status = importRejected
+			result.Reasons = []string{"Ticket limit per person reached."}
+		case errors.Is(err, booking.ErrSalesClosed):
+			return results, err
+		case err != nil:
+			result.Status = importRejected
+			result.Reasons = []string{err.Error()}
+		case opts.DryRun:
+			result.Status = importWouldBook
+			result.FirstName, result.LastName = userData.FirstName, userData.LastName
+		default:
+			result.Status = importBooked
+			result.FirstName, result.LastName = userData.FirstName, userData.LastName
+			result.BookingID = userData.ID
+			result.ConfirmationCode = booking.FormatConfirmationCode(userData.ConfirmationCode)
+			if opts.SendTickets {
+				if err := sendTicket(deliveries, conference, userData); err != nil {
+					result.Reasons = []string{fmt.Sprintf("Ticket not sent: %v", err)}
+				}
+			}
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// soldOutReason explains why a row cannot get its tickets, or returns ""
+// if there are enough left. Unknown tiers are left to validateUserInput.
+func soldOutReason(conference *booking.Conference, tierID string, tickets uint) string {
+	tier, err := conference.Tier(tierID)
+	if err != nil || tickets == 0 {
+		return ""
+	}
+	remaining := conference.RemainingInTier(tier.ID)
+	if remaining == 0 {
+		return fmt.Sprintf("%v tickets are sold out.", tier.Name)
+	}
+	if tickets > remaining {
+		return fmt.Sprintf("Only %v %v tickets left.", remaining, tier.Name)
+	}
+	return ""
+}
+
+// printImportReport prints one line per row and a summary
+func printImportReport(out io.Writer, results []importResult, dryRun bool) {
+	counts := make(map[string]int)
+	for _, result := range results {
+		counts[result.Status]++
+		name := strings.TrimSpace(result.FirstName + " " + result.LastName)
+		switch result.Status {
+		case importBooked:
+			fmt.Fprintf(out, "Row %v: booked %v tickets for %v, booking %v, confirmation code %v", result.Row, result.NumberOfTickets, name, result.BookingID, result.ConfirmationCode)
+		case importWouldBook:
+			fmt.Fprintf(out, "Row %v: would book %v tickets for %v", result.Row, result.NumberOfTickets, name)
+		default:
+			fmt.Fprintf(out, "Row %v: %v", result.Row, strings.TrimSpace(result.Status+" "+name))
+		}
+		if len(result.Reasons) > 0 {
+			fmt.Fprintf(out, ": %v", strings.Join(result.Reasons, " "))
+		}
+		fmt.Fprintln(out)
+	}
+
+	if dryRun {
+		fmt.Fprintf(out, "Dry run: %v rows, %v would be booked, %v rejected, %v skipped\n", len(results), counts[importWouldBook], counts[importRejected], counts[importSkipped])
+		return
+	}
+	fmt.Fprintf(out, "%v rows: %v booked, %v rejected, %v skipped\n", len(results), counts[importBooked], counts[importRejected], counts[importSkipped])
+}
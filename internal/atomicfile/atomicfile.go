@@ -0,0 +1,84 @@
+// Package atomicfile replaces files so that readers, and the application
+// after a crash or power loss, only ever see the old or the new contents of
+// a file.
+package atomicfile
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// Write replaces the file at path with what write writes. The data goes to
+// a temporary file next to path, which is synced to disk and renamed over
+// path once it is complete; the directory is synced after the rename so the
+// new name survives a power loss too. A crash or a failing write never
+// leaves a half-written file.
+func Write(path string, write func(w io.Writer) error) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	if err := write(tmp); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return syncDir(filepath.Dir(path))
+}
+
+// WriteJSON replaces the file at path with v encoded as indented JSON
+func WriteJSON(path string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	return Write(path, func(w io.Writer) error {
+		_, err := w.Write(data)
+		return err
+	})
+}
+
+// ReadJSON decodes the JSON file at path into v. A missing file is reported
+// with an error matching os.ErrNotExist. Files written by WriteJSON are never
+// empty, so an empty file is an error like an unparseable one: treating it
+// as no data would silently lose what it should have held.
+func ReadJSON(path string, v interface{}) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if len(data) == 0 {
+		return fmt.Errorf("%v is empty", path)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("%v: %w", path, err)
+	}
+	return nil
+}
+
+// syncDir flushes the directory entry of a renamed file to disk
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
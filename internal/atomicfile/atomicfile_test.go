@@ -0,0 +1,64 @@
+package atomicfile
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteJSONRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "data.json")
+	if err := WriteJSON(path, []string{"a", "b"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteJSON(path, []string{"c"}); err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	if err := ReadJSON(path, &got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0] != "c" {
+		t.Errorf("ReadJSON = %v, want [c]", got)
+	}
+	if files, _ := ioutil.ReadDir(dir); len(files) != 1 {
+		t.Errorf("%v files in the directory, want only data.json", len(files))
+	}
+}
+
+func TestWriteKeepsOldContentsOnError(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data.json")
+	if err := WriteJSON(path, 1); err != nil {
+		t.Fatal(err)
+	}
+	failed := errors.New("failed")
+	if err := Write(path, func(w io.Writer) error { return failed }); err != failed {
+		t.Fatalf("Write = %v, want the error of write", err)
+	}
+	var got int
+	if err := ReadJSON(path, &got); err != nil || got != 1 {
+		t.Errorf("ReadJSON = %v, %v, want the old contents 1", got, err)
+	}
+}
+
+func TestReadJSONRejectsMissingEmptyAndDamagedFiles(t *testing.T) {
+	dir := t.TempDir()
+	var v []string
+	if err := ReadJSON(filepath.Join(dir, "missing.json"), &v); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("ReadJSON of a missing file = %v, want os.ErrNotExist", err)
+	}
+	for name, data := range map[string]string{"empty.json": "", "damaged.json": `[{"id": 1`} {
+		path := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(path, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := ReadJSON(path, &v); err == nil || errors.Is(err, os.ErrNotExist) {
+			t.Errorf("ReadJSON of %v = %v, want an error", name, err)
+		}
+	}
+}
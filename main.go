@@ -1,144 +1,531 @@
 // Capstone: Booking Application
 // This is the final project that integrates all concepts learned in the tutorial,
 // including structs, functions, loops, validation, and concurrency.
+// The booking engine itself lives in the 'booking' package; this file is a thin CLI over it.
 package main
 
 import (
+	"booking-app/booking"
+	"booking-app/notify"
+	"context"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
 	"sync"
+	"syscall"
 	"time"
 )
 
-// Package-level constants and variables
-const conferenceTickets int = 50
+// Package-level constants used as the defaults of the default conference
+const conferenceTickets uint = 50
+const conferenceName = "Go Conference"
 
-var conferenceName = "Go Conference"
-var remainingTickets uint = 50
-var bookings = make([]UserData, 0)
+// defaultEvent is the conference offered when no events file is given.
+// main replaces its name and tickets with the configured ones.
+var defaultEvent = booking.Event{ID: "go-conference", Name: conferenceName, Tickets: conferenceTickets}
 
-// UserData groups all information about a single booking
-type UserData struct {
-	firstName       string
-	lastName        string
-	email           string
-	numberOfTickets uint
-}
-
-// sync.WaitGroup is used to wait for all asynchronous tasks (sending emails) to finish
+// sync.WaitGroup is used to wait until every ticket delivery has been reported
 var wg = sync.WaitGroup{}
 
+// promptBookingMu is held by the prompt from booking an order until its
+// ticket is queued, so an interrupt can wait for the ticket before the
+// delivery queue shuts down
+var promptBookingMu sync.Mutex
+
 func main() {
+	// Settings come from -config or BOOKING_CONFIG, BOOKING_* variables and flags
+	cfg, err := loadConfig(os.Args[1:], os.LookupEnv)
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(0)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	nameRules = cfg.NameRules
+	defaultEvent.Name = cfg.ConferenceName
+	defaultEvent.Tickets = cfg.ConferenceTickets
+
+	// ctx is cancelled on the first SIGINT or SIGTERM
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := setupEmailRules(cfg.EmailStrictness, cfg.EmailBlocklist); err != nil {
+		log.Fatal(err)
+	}
+
+	registry, err := openRegistry(cfg.EventsFile, cfg.DataDir)
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, conference := range registry.List() {
+		conference.SetTicketLimits(cfg.TicketLimits)
+	}
+
+	notifier, err := notify.New(cfg.Notify)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	cfg.Queue.DeadLetters = notify.NewDeadLetterFile(cfg.DeadLetters)
+
+	// The first argument names the command, e.g. "list" or "import file.csv"
+	env := &environment{ctx: ctx, cfg: cfg, registry: registry, notifier: notifier}
+	code := runCommand(env, cfg.Args)
+	env.stopDeliveries()
+	stop()
+	os.Exit(code)
+}
+
+// runPrompt books tickets interactively, putting requests for sold out
+// tickets on the waitlist. It returns when the input ends or sales close.
+func runPrompt(p *prompter, registry *booking.Registry, deliveries *notify.Queue) {
 	// Greet the user and show initial state
-	greetUsers()
+	greetUsers(p, registry)
 
 	for {
-		// 1. Collect user information
-		firstName, lastName, email, userTickets := getUserInput()
+		err := promptBooking(p, registry, deliveries)
+		switch {
+		case errors.Is(err, io.EOF):
+			p.printf("\nNo more input. Goodbye!\n")
+			return
+		case errors.Is(err, booking.ErrSalesClosed):
+			return
+		case errors.Is(err, errTooManyRetries):
+			p.printf("Too many invalid answers, starting over.\n")
+		case err != nil:
+			p.printf("Error: %v\n", err)
+		}
+	}
+}
+
+// promptBooking asks for and books one order. Answers that cannot be
+// booked are reported to the user; only errors that end the booking
+// conversation are returned.
+func promptBooking(p *prompter, registry *booking.Registry, deliveries *notify.Queue) error {
+	// 1. Let the user pick a conference
+	conference, err := chooseConference(p, registry)
+	if err != nil {
+		return err
+	}
 
-		// 2. Validate user input using logic in helper.go
-		isValidName, isValidEmail, isValidTicketNumber := validateUserInput(firstName, lastName, email, userTickets)
+	// 2. Let the user pick a ticket type if the conference sells several
+	tier, err := chooseTier(p, conference)
+	if err != nil {
+		return err
+	}
 
-		if isValidName && isValidEmail && isValidTicketNumber {
-			// 3. Update the booking records
-			bookTicket(userTickets, firstName, lastName, email)
+	// 3. Collect user information
+	firstName, lastName, email, userTickets, err := getUserInput(p)
+	if err != nil {
+		return err
+	}
+	orders := []booking.TicketOrder{{Tier: tier.ID, Quantity: userTickets}}
 
-			// 4. Start an asynchronous task to "send" the ticket
-			// We increment the WaitGroup counter before starting the goroutine.
-			wg.Add(1)
-			go sendTicket(userTickets, firstName, lastName, email)
+	// Requests for more tickets than are left can wait for cancellations
+	if userTickets > conference.RemainingInTier(tier.ID) {
+		return joinWaitlist(p, conference, tier, firstName, lastName, email, userTickets)
+	}
+	promoCode, err := getPromoCode(p, conference)
+	if err != nil {
+		return err
+	}
 
-			// 5. Display current bookings
-			firstNames := getFirstNames()
-			fmt.Printf("Current bookings (first names): %v\n", firstNames)
+	// 4. Validate user input using logic in helper.go
+	if err := validateUserInput(conference, firstName, lastName, email, orders, promoCode); err != nil {
+		printValidationError(p.out, err)
+		return nil
+	}
 
-			// 6. Check if the conference is sold out
-			if remainingTickets == 0 {
-				fmt.Println("The conference is fully booked. See you next year!")
-				break
-			}
-		} else {
-			// Specific error messages for invalid input
-			if !isValidName {
-				fmt.Println("Error: First or last name is too short (min 2 chars).")
-			}
-			if !isValidEmail {
-				fmt.Println("Error: Email address must contain an '@' symbol.")
-			}
-			if !isValidTicketNumber {
-				fmt.Printf("Error: Invalid number of tickets. Only %v remaining.\n", remainingTickets)
-			}
+	// 5. Update the booking records
+	// Availability is checked again atomically while booking,
+	// because another caller may have booked in the meantime.
+	order := booking.Order{FirstName: firstName, LastName: lastName, Email: email, Items: orders, PromoCode: promoCode}
+	promptBookingMu.Lock()
+	userData, err := bookTicket(p, conference, order)
+	if errors.Is(err, booking.ErrSalesClosed) {
+		promptBookingMu.Unlock()
+		return err
+	}
+	if err != nil {
+		promptBookingMu.Unlock()
+		p.printf("Error: %v\n", err)
+		return nil
+	}
+
+	// 6. Queue the ticket; one of the delivery workers sends it in the background
+	err = sendTicket(deliveries, conference, userData)
+	promptBookingMu.Unlock()
+	if err != nil {
+		p.printf("Error: %v\n", err)
+	}
+	p.printf("Tickets waiting to be sent: %v\n", deliveries.Depth())
+
+	// 7. Display current bookings
+	firstNames, err := conference.FirstNames()
+	if err != nil {
+		log.Fatal(err)
+	}
+	p.printf("Current bookings (first names): %v\n", firstNames)
+
+	// 8. Check if the conference is sold out
+	if conference.Remaining() == 0 {
+		p.printf("%v is now fully booked. Further requests join the waitlist.\n", conference.Name())
+	}
+	return nil
+}
+
+// serve runs the HTTP API until ctx is cancelled, then lets running requests finish
+func serve(ctx context.Context, addr string, registry *booking.Registry, deliveries *notify.Queue, holdTTL time.Duration, timeout time.Duration) {
+	server := &http.Server{Addr: addr, Handler: newServer(registry, deliveries, holdTTL)}
+
+	go func() {
+		fmt.Printf("Serving the Booking API for %v conferences on %v\n", len(registry.List()), addr)
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Fatal(err)
 		}
+	}()
+
+	<-ctx.Done()
+	fmt.Println("Interrupted, no longer accepting bookings.")
+	registry.CloseSales()
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Printf("Stopping the HTTP server: %v", err)
+	}
+}
+
+// shutdownDeliveries sends the queued tickets within timeout and reports the ones left over
+func shutdownDeliveries(deliveries *notify.Queue, timeout time.Duration) {
+	if depth := deliveries.Depth(); depth > 0 {
+		fmt.Printf("Sending %v remaining tickets (up to %v)...\n", depth, timeout)
 	}
 
-	// Wait for all background goroutines (email sending) to complete before exiting
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	undelivered := deliveries.Shutdown(ctx)
+
+	// Wait for the last delivery report before exiting
 	wg.Wait()
+
+	if len(undelivered) > 0 {
+		fmt.Printf("%v tickets were not sent before the deadline and were kept as dead letters:\n", len(undelivered))
+		for _, msg := range undelivered {
+			fmt.Printf("  %v (%v)\n", msg.To, msg.Subject)
+		}
+	}
+}
+
+// offerWaitlistTickets emails an offer through the delivery queue whenever
+// freed tickets are held for someone on the waitlist of any conference
+func offerWaitlistTickets(registry *booking.Registry, deliveries *notify.Queue, window time.Duration) {
+	for _, conference := range registry.List() {
+		conference.SetWaitlistOffers(window, func(event booking.Event, entry booking.WaitlistEntry) {
+			if err := deliveries.Enqueue(notify.WaitlistOfferMessage(event, entry)); err != nil {
+				log.Printf("Queueing waitlist offer for %v: %v", entry.Email, err)
+			}
+		})
+	}
+}
+
+// openRegistry sets up the conferences listed in eventsFile, or the default
+// conference if no file is given. With a dataDir each conference keeps its
+// bookings in <dataDir>/<id>.json and continues where it left off.
+func openRegistry(eventsFile string, dataDir string) (*booking.Registry, error) {
+	events := []booking.Event{defaultEvent}
+	if eventsFile != "" {
+		var err error
+		events, err = booking.LoadEvents(eventsFile)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if dataDir != "" {
+		if err := os.MkdirAll(dataDir, 0o755); err != nil {
+			return nil, err
+		}
+	}
+
+	registry := booking.NewRegistry()
+	for _, event := range events {
+		conference, err := openConference(event, dataDir)
+		if err != nil {
+			return nil, err
+		}
+		if err := registry.Add(conference); err != nil {
+			return nil, err
+		}
+	}
+	return registry, nil
 }
 
-// greetUsers prints the application header
-func greetUsers() {
-	fmt.Printf("Welcome to the %v Booking Application\n", conferenceName)
-	fmt.Printf("Total Tickets: %v | Available: %v\n", conferenceTickets, remainingTickets)
-	fmt.Println("--------------------------------------------------")
+// openConference sets up one conference, in memory or backed by a file in dataDir
+func openConference(event booking.Event, dataDir string) (*booking.Conference, error) {
+	if dataDir == "" {
+		return booking.NewConference(event), nil
+	}
+
+	store, err := booking.NewFileStore(filepath.Join(dataDir, event.ID+".json"))
+	if err != nil {
+		return nil, err
+	}
+	conference, err := booking.OpenConference(event, store)
+	if err != nil {
+		return nil, err
+	}
+
+	// Cancellations are audited next to the bookings
+	audit, err := booking.NewFileAuditLog(filepath.Join(dataDir, event.ID+".cancellations.json"))
+	if err != nil {
+		return nil, err
+	}
+	if err := conference.SetAuditLog(audit); err != nil {
+		return nil, err
+	}
+
+	// So is the waitlist, so the people on it are still served after a restart
+	waitlist, err := booking.NewFileWaitlistStore(filepath.Join(dataDir, event.ID+".waitlist.json"))
+	if err != nil {
+		return nil, err
+	}
+	if err := conference.SetWaitlistStore(waitlist); err != nil {
+		return nil, err
+	}
+	return conference, nil
 }
 
-// getFirstNames extracts a list of first names from the bookings slice
-func getFirstNames() []string {
-	firstNames := []string{}
-	for _, booking := range bookings {
-		firstNames = append(firstNames, booking.firstName)
+// printValidationError prints one specific error message per broken rule
+func printValidationError(out io.Writer, err error) {
+	var validationErr *booking.ValidationError
+	if !errors.As(err, &validationErr) {
+		fmt.Fprintf(out, "Error: %v\n", err)
+		return
+	}
+	for _, fieldError := range validationErr.Errors {
+		fmt.Fprintf(out, "Error: %v\n", fieldError.Message)
 	}
-	return firstNames
 }
 
-// getUserInput prompts the user and collects data from stdin
-func getUserInput() (string, string, string, uint) {
-	var firstName string
-	var lastName string
-	var email string
-	var userTickets uint
+// setupEmailRules configures the email validation used by validateUserInput
+func setupEmailRules(strictness string, blocklistFile string) error {
+	var err error
+	emailRules.Strictness, err = booking.ParseEmailStrictness(strictness)
+	if err != nil {
+		return err
+	}
 
-	fmt.Println("\nEnter your first name: ")
-	fmt.Scan(&firstName)
+	if blocklistFile != "" {
+		emailRules.Blocklist, err = booking.LoadEmailBlocklist(blocklistFile)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
 
-	fmt.Println("Enter your last name: ")
-	fmt.Scan(&lastName)
+// greetUsers prints the application header with every conference on offer
+func greetUsers(p *prompter, registry *booking.Registry) {
+	p.printf("Welcome to the Conference Booking Application\n")
+	for _, conference := range registry.List() {
+		p.printf("%v | Total Tickets: %v | Available: %v\n", conference.Name(), conference.Tickets(), conference.Remaining())
+	}
+	p.printf("--------------------------------------------------\n")
+}
+
+// chooseConference asks which conference to book when there are several.
+// Sold out conferences are still listed so people can join their waitlist.
+func chooseConference(p *prompter, registry *booking.Registry) (*booking.Conference, error) {
+	conferences := registry.List()
+	if len(conferences) == 1 {
+		return conferences[0], nil
+	}
 
-	fmt.Println("Enter your email address: ")
-	fmt.Scan(&email)
+	p.printf("\nChoose a conference:\n")
+	for i, conference := range conferences {
+		p.printf("  %v. %v%v (%v)\n", i+1, conference.Name(), eventDates(conference.Event()), ticketsLeft(conference.Remaining()))
+	}
+	choice, err := p.askNumber("Enter the number of the conference: ", 1, uint(len(conferences)))
+	if err != nil {
+		return nil, err
+	}
+	return conferences[choice-1], nil
+}
 
-	fmt.Println("Enter number of tickets: ")
-	fmt.Scan(&userTickets)
+// chooseTier asks which ticket type to book when the conference sells several.
+// Conferences with a single ticket type skip the question.
+func chooseTier(p *prompter, conference *booking.Conference) (booking.Tier, error) {
+	tiers := conference.Tiers()
+	if len(tiers) == 1 {
+		return tiers[0], nil
+	}
 
-	return firstName, lastName, email, userTickets
+	currency := conference.Event().Currency
+	p.printf("\nChoose a ticket type:\n")
+	for i, tier := range tiers {
+		p.printf("  %v. %v at %v (%v)\n", i+1, tier.Name, booking.FormatAmount(tier.Price, currency), ticketsLeft(conference.RemainingInTier(tier.ID)))
+	}
+	choice, err := p.askNumber("Enter the number of the ticket type: ", 1, uint(len(tiers)))
+	if err != nil {
+		return booking.Tier{}, err
+	}
+	return tiers[choice-1], nil
 }
 
-// bookTicket updates the remaining tickets and adds the user to the list
-func bookTicket(userTickets uint, firstName string, lastName string, email string) {
-	remainingTickets = remainingTickets - userTickets
+// ticketsLeft describes the remaining tickets for a menu entry
+func ticketsLeft(remaining uint) string {
+	if remaining == 0 {
+		return "sold out, waitlist open"
+	}
+	return fmt.Sprintf("%v tickets left", remaining)
+}
 
-	var userData = UserData{
-		firstName:       firstName,
-		lastName:        lastName,
-		email:           email,
-		numberOfTickets: userTickets,
+// joinWaitlist offers a place on the waitlist when not enough tickets are left
+func joinWaitlist(p *prompter, conference *booking.Conference, tier booking.Tier, firstName string, lastName string, email string, userTickets uint) error {
+	question := fmt.Sprintf("Only %v %v tickets are left. Join the waitlist for %v tickets? (y/n)", conference.RemainingInTier(tier.ID), tier.Name, userTickets)
+	join, err := p.askYesNo(question)
+	if err != nil || !join {
+		return err
 	}
 
-	bookings = append(bookings, userData)
+	if err := validatePersonalDetails(conference, firstName, lastName, email, userTickets); err != nil {
+		printValidationError(p.out, err)
+		return nil
+	}
 
-	fmt.Printf("Success! %v %v booked %v tickets. Confirmation sent to %v\n", firstName, lastName, userTickets, email)
-	fmt.Printf("Tickets remaining: %v\n", remainingTickets)
+	entry, err := conference.JoinWaitlist(firstName, lastName, email, tier.ID, userTickets)
+	if err != nil {
+		p.printf("Error: %v\n", err)
+		return nil
+	}
+	p.printf("%v, you are number %v on the waitlist. We will email %v when tickets are free.\n", entry.FirstName, len(conference.Waitlist()), entry.Email)
+	return nil
 }
 
-// sendTicket simulates a long-running process (like sending an email) using a goroutine
-func sendTicket(userTickets uint, firstName string, lastName string, email string) {
-	// Simulate a 10-second delay for email processing
-	time.Sleep(10 * time.Second)
+// eventDates formats the dates of an event for display, if it has any
+func eventDates(event booking.Event) string {
+	if event.Starts.IsZero() {
+		return ""
+	}
+	if event.Ends.IsZero() || event.Ends.Format("2006-01-02") == event.Starts.Format("2006-01-02") {
+		return " on " + event.Starts.Format("2006-01-02")
+	}
+	return " from " + event.Starts.Format("2006-01-02") + " to " + event.Ends.Format("2006-01-02")
+}
 
-	var ticket = fmt.Sprintf("%v tickets for %v %v", userTickets, firstName, lastName)
-	fmt.Println("\n##################################################")
-	fmt.Printf("SIMULATED EMAIL: Sending ticket...\n%v\nto address %v\n", ticket, email)
-	fmt.Println("##################################################")
+// getUserInput asks for the details of the person booking, one line per
+// field, asking again for a field until it is valid on its own
+func getUserInput(p *prompter) (firstName string, lastName string, email string, userTickets uint, err error) {
+	p.printf("\n")
+	firstName, err = p.ask("Enter your first name: ", func(answer string) error {
+		return nameError("First name", answer)
+	})
+	if err != nil {
+		return "", "", "", 0, err
+	}
+
+	lastName, err = p.ask("Enter your last name: ", func(answer string) error {
+		return nameError("Last name", answer)
+	})
+	if err != nil {
+		return "", "", "", 0, err
+	}
+
+	email, err = p.ask("Enter your email address: ", func(answer string) error {
+		if err := booking.ValidateEmail(answer, emailRules); errors.Is(err, booking.ErrDisposableEmail) {
+			return errors.New("Email addresses from this domain are not accepted.")
+		} else if err != nil {
+			return fmt.Errorf("Invalid email address: %v.", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return "", "", "", 0, err
+	}
+
+	userTickets, err = p.askNumber("Enter number of tickets: ", 1, 0)
+	if err != nil {
+		return "", "", "", 0, err
+	}
+	return firstName, lastName, email, userTickets, nil
+}
+
+// nameError checks a first or last name, describing the problem for the prompt
+func nameError(field string, name string) error {
+	var nameErr *booking.NameError
+	if errors.As(booking.ValidateName(name, nameRules), &nameErr) {
+		return fmt.Errorf("%v %v.", field, nameErr.Message)
+	}
+	return nil
+}
+
+// getPromoCode asks for a promo code if the conference accepts any.
+// An empty line or "-" books without a code.
+func getPromoCode(p *prompter, conference *booking.Conference) (string, error) {
+	if len(conference.Event().PromoCodes) == 0 {
+		return "", nil
+	}
+
+	promoCode, err := p.readLine("Enter a promo code (or - for none): ")
+	if err != nil || promoCode == "-" {
+		return "", err
+	}
+	return promoCode, nil
+}
+
+// bookTicket records the booking in the conference and prints a confirmation with the price
+func bookTicket(p *prompter, conference *booking.Conference, order booking.Order) (booking.UserData, error) {
+	userData, err := conference.BookOrder(order)
+	if err != nil {
+		return booking.UserData{}, err
+	}
+
+	p.printf("Success! %v %v booked %v tickets. Confirmation sent to %v\n", userData.FirstName, userData.LastName, userData.NumberOfTickets, userData.Email)
+	printBookingDetails(p.out, conference, userData)
+	p.printf("Tickets remaining: %v\n", conference.Remaining())
+	return userData, nil
+}
+
+// printBookingDetails prints the confirmation code and the price of every line item
+func printBookingDetails(out io.Writer, conference *booking.Conference, userData booking.UserData) {
+	fmt.Fprintf(out, "Booking %v, confirmation code %v\n", userData.ID, booking.FormatConfirmationCode(userData.ConfirmationCode))
+	for _, item := range userData.LineItems {
+		tier, _ := conference.Tier(item.Tier)
+		fmt.Fprintf(out, "  %v x %v at %v = %v\n", item.Quantity, tier.Name,
+			booking.FormatAmount(item.UnitPrice, userData.Currency), booking.FormatAmount(item.Total, userData.Currency))
+	}
+	if userData.PromoCode != "" {
+		fmt.Fprintf(out, "  Promo code %v: -%v\n", userData.PromoCode, booking.FormatAmount(userData.Discount, userData.Currency))
+	}
+	fmt.Fprintf(out, "Total: %v\n", booking.FormatAmount(userData.Total, userData.Currency))
+}
+
+// sendTicket queues the ticket for delivery by the worker pool
+func sendTicket(deliveries *notify.Queue, conference *booking.Conference, userData booking.UserData) error {
+	return deliveries.Enqueue(notify.TicketMessage(conference.Event(), userData))
+}
+
+// sendCancellation queues the cancellation notice through the same path as the tickets
+func sendCancellation(deliveries *notify.Queue, conference *booking.Conference, cancellation booking.Cancellation) error {
+	return deliveries.Enqueue(notify.CancellationMessage(conference.Event(), cancellation))
+}
+
+// reportDeliveries logs the result of every ticket delivery until the queue is closed
+func reportDeliveries(deliveries *notify.Queue) {
+	// Notify the WaitGroup that all results have been reported
+	defer wg.Done()
 
-	// Notify the WaitGroup that this task is complete
-	wg.Done()
+	for result := range deliveries.Results() {
+		if result.Err != nil && !errors.Is(result.Err, context.Canceled) {
+			log.Printf("Sending ticket to %v failed after %v attempts, kept as dead letter: %v", result.Message.To, result.Attempts, result.Err)
+		}
+	}
 }
@@ -0,0 +1,97 @@
+package notify
+
+import (
+	"booking-app/internal/atomicfile"
+	"context"
+	"errors"
+	"os"
+	"sync"
+	"time"
+)
+
+// DeadLetter is a message that could not be delivered after all retries
+type DeadLetter struct {
+	Message   Message   `json:"message"`
+	Attempts  int       `json:"attempts"`
+	LastError string    `json:"lastError"`
+	FailedAt  time.Time `json:"failedAt"`
+}
+
+// DeadLetterFile keeps undeliverable messages in a JSON file,
+// so an operator can inspect them and send them again later.
+type DeadLetterFile struct {
+	mu   sync.Mutex
+	path string
+}
+
+// NewDeadLetterFile uses the JSON file at path; it is created on the first Add
+func NewDeadLetterFile(path string) *DeadLetterFile {
+	return &DeadLetterFile{path: path}
+}
+
+// Add appends a dead letter to the file
+func (f *DeadLetterFile) Add(deadLetter DeadLetter) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	deadLetters, err := f.read()
+	if err != nil {
+		return err
+	}
+	return f.write(append(deadLetters, deadLetter))
+}
+
+// List returns all dead letters in the order they failed
+func (f *DeadLetterFile) List() ([]DeadLetter, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.read()
+}
+
+// Redrive sends every dead letter again through notifier using policy.
+// Messages that are delivered are removed from the file; the others stay
+// with their attempt count and error updated. It returns how many were delivered.
+func (f *DeadLetterFile) Redrive(ctx context.Context, notifier Notifier, policy RetryPolicy) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	deadLetters, err := f.read()
+	if err != nil {
+		return 0, err
+	}
+
+	delivered := 0
+	remaining := make([]DeadLetter, 0)
+	for _, deadLetter := range deadLetters {
+		attempts, err := sendWithRetry(ctx, notifier, deadLetter.Message, policy)
+		if err == nil {
+			delivered++
+			continue
+		}
+		deadLetter.Attempts += attempts
+		deadLetter.LastError = err.Error()
+		deadLetter.FailedAt = time.Now()
+		remaining = append(remaining, deadLetter)
+	}
+	return delivered, f.write(remaining)
+}
+
+// read loads the dead letters; a missing file means there are none
+func (f *DeadLetterFile) read() ([]DeadLetter, error) {
+	deadLetters := make([]DeadLetter, 0)
+
+	err := atomicfile.ReadJSON(f.path, &deadLetters)
+	if errors.Is(err, os.ErrNotExist) {
+		return deadLetters, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return deadLetters, nil
+}
+
+// write replaces the file through a temporary file and a rename
+func (f *DeadLetterFile) write(deadLetters []DeadLetter) error {
+	return atomicfile.WriteJSON(f.path, deadLetters)
+}
@@ -0,0 +1,53 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+)
+
+// MaildirNotifier writes every message as a file into a maildir,
+// so deliveries can be inspected by hand or asserted on in tests.
+type MaildirNotifier struct {
+	dir      string
+	hostname string
+	counter  uint64
+}
+
+// NewMaildirNotifier creates the tmp, new and cur folders of the maildir at dir
+func NewMaildirNotifier(dir string) (*MaildirNotifier, error) {
+	if dir == "" {
+		return nil, fmt.Errorf("notify: maildir directory is not set")
+	}
+	for _, sub := range []string{"tmp", "new", "cur"} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0o755); err != nil {
+			return nil, err
+		}
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "localhost"
+	}
+	return &MaildirNotifier{dir: dir, hostname: hostname}, nil
+}
+
+// Send writes the message into tmp and then moves it into new,
+// so readers of the maildir never see a partially written message.
+func (n *MaildirNotifier) Send(ctx context.Context, msg Message) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	name := fmt.Sprintf("%v.%v_%v.%v", time.Now().UnixNano(), os.Getpid(), atomic.AddUint64(&n.counter, 1), n.hostname)
+	tmpPath := filepath.Join(n.dir, "tmp", name)
+
+	if err := ioutil.WriteFile(tmpPath, formatMessage("", msg), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, filepath.Join(n.dir, "new", name))
+}
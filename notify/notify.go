@@ -0,0 +1,164 @@
+// Package notify delivers booking confirmations to attendees.
+// A Notifier hides how a message travels (SMTP, files on disk, stdout),
+// so the booking application only has to pick one through Config.
+package notify
+
+import (
+	"booking-app/booking"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Message is a plain-text email
+type Message struct {
+	To      string `json:"to"`
+	Subject string `json:"subject"`
+	Body    string `json:"body"`
+}
+
+// Notifier sends messages to attendees.
+// Send must give up and return the context's error once ctx is done.
+type Notifier interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// Config selects and configures a Notifier.
+// Kind is "stdout", "maildir" or "smtp"; the other fields only apply to some kinds.
+type Config struct {
+	Kind string
+
+	// Delay simulates a slow mail backend for the stdout notifier
+	Delay time.Duration
+
+	// Dir is the maildir that the maildir notifier writes to
+	Dir string
+
+	// SMTP server address (host:port), sender and optional PLAIN auth credentials
+	SMTPAddr     string
+	From         string
+	SMTPUsername string
+	SMTPPassword string
+}
+
+// New creates the Notifier described by cfg
+func New(cfg Config) (Notifier, error) {
+	switch cfg.Kind {
+	case "stdout", "":
+		return &StdoutNotifier{Out: os.Stdout, Delay: cfg.Delay}, nil
+	case "maildir":
+		return NewMaildirNotifier(cfg.Dir)
+	case "smtp":
+		return NewSMTPNotifier(cfg.SMTPAddr, cfg.From, cfg.SMTPUsername, cfg.SMTPPassword)
+	}
+	return nil, fmt.Errorf("notify: unknown notifier %q", cfg.Kind)
+}
+
+// TicketMessage builds the confirmation email for a booking of the event
+func TicketMessage(event booking.Event, userData booking.UserData) Message {
+	var body strings.Builder
+	fmt.Fprintf(&body, "Hello %v,\n\n%v tickets for %v %v to %v.\n",
+		userData.FirstName, userData.NumberOfTickets, userData.FirstName, userData.LastName, event.Name)
+	if userData.ConfirmationCode != "" {
+		fmt.Fprintf(&body, "Booking %v, confirmation code %v\n", userData.ID, booking.FormatConfirmationCode(userData.ConfirmationCode))
+	}
+
+	if len(userData.LineItems) > 0 {
+		body.WriteString("\n")
+		for _, item := range userData.LineItems {
+			fmt.Fprintf(&body, "  %v x %v at %v = %v\n", item.Quantity, tierName(event, item.Tier),
+				booking.FormatAmount(item.UnitPrice, userData.Currency), booking.FormatAmount(item.Total, userData.Currency))
+		}
+		if userData.PromoCode != "" {
+			fmt.Fprintf(&body, "  Promo code %v: -%v\n", userData.PromoCode, booking.FormatAmount(userData.Discount, userData.Currency))
+		}
+		fmt.Fprintf(&body, "Total: %v\n", booking.FormatAmount(userData.Total, userData.Currency))
+	}
+
+	return Message{
+		To:      userData.Email,
+		Subject: fmt.Sprintf("Your tickets for %v", event.Name),
+		Body:    body.String(),
+	}
+}
+
+// CancellationMessage confirms a cancellation and the refund to the attendee
+func CancellationMessage(event booking.Event, cancellation booking.Cancellation) Message {
+	var body strings.Builder
+	fmt.Fprintf(&body, "Hello %v,\n\n", cancellation.FirstName)
+	if cancellation.Full {
+		fmt.Fprintf(&body, "Your booking %v for %v has been cancelled.\n", cancellation.BookingID, event.Name)
+	} else {
+		fmt.Fprintf(&body, "%v tickets of your booking %v for %v have been cancelled.\n", cancellation.NumberOfTickets, cancellation.BookingID, event.Name)
+	}
+
+	body.WriteString("\n")
+	for _, item := range cancellation.Items {
+		fmt.Fprintf(&body, "  %v x %v\n", item.Quantity, tierName(event, item.Tier))
+	}
+	if cancellation.Fee > 0 {
+		fmt.Fprintf(&body, "Cancellation fee: %v\n", booking.FormatAmount(cancellation.Fee, cancellation.Currency))
+	}
+	fmt.Fprintf(&body, "Refund: %v\n", booking.FormatAmount(cancellation.Refund, cancellation.Currency))
+
+	return Message{
+		To:      cancellation.Email,
+		Subject: fmt.Sprintf("Cancellation of your tickets for %v", event.Name),
+		Body:    body.String(),
+	}
+}
+
+// WaitlistOfferMessage tells someone on the waitlist that tickets are held for them
+func WaitlistOfferMessage(event booking.Event, entry booking.WaitlistEntry) Message {
+	var body strings.Builder
+	fmt.Fprintf(&body, "Hello %v,\n\n%v %v tickets for %v are now free and held for you.\n",
+		entry.FirstName, entry.NumberOfTickets, tierName(event, entry.Tier), event.Name)
+	fmt.Fprintf(&body, "Accept the offer (waitlist number %v) before %v to book them.\n",
+		entry.ID, entry.OfferExpires.Format("Mon Jan 2 15:04 MST"))
+
+	return Message{
+		To:      entry.Email,
+		Subject: fmt.Sprintf("Tickets for %v are available", event.Name),
+		Body:    body.String(),
+	}
+}
+
+// tierName returns the display name of a tier, falling back to its ID
+func tierName(event booking.Event, tierID string) string {
+	for _, tier := range event.TicketTiers() {
+		if tier.ID == tierID {
+			return tier.Name
+		}
+	}
+	return tierID
+}
+
+// StdoutNotifier prints messages instead of sending them, for demos and development
+type StdoutNotifier struct {
+	Out   io.Writer
+	Delay time.Duration
+
+	// mu keeps messages from concurrent senders from interleaving
+	mu sync.Mutex
+}
+
+// Send waits for Delay and then prints the message
+func (n *StdoutNotifier) Send(ctx context.Context, msg Message) error {
+	select {
+	case <-time.After(n.Delay):
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	fmt.Fprintln(n.Out, "\n##################################################")
+	fmt.Fprintf(n.Out, "SIMULATED EMAIL: Sending ticket...\n%v\nto address %v\n", msg.Body, msg.To)
+	fmt.Fprintln(n.Out, "##################################################")
+	return nil
+}
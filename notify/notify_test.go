@@ -0,0 +1,265 @@
+package notify
+
+import (
+	"booking-app/booking"
+	"context"
+	"errors"
+	"io/ioutil"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testEvent and testBooking make up the confirmation used by the tests
+var testEvent = booking.Event{ID: "go-conference", Name: "Go Conference", Currency: "EUR",
+	Tiers: []booking.Tier{{ID: "general", Name: "General", Price: 1250, Tickets: 10}}}
+
+var testBooking = booking.UserData{
+	ID:               7,
+	ConfirmationCode: "ACDEFGHJ",
+	FirstName:        "Mary",
+	LastName:         "van Berg",
+	Email:            "mary@example.com",
+	NumberOfTickets:  2,
+	LineItems:        []booking.LineItem{{Tier: "general", Quantity: 2, UnitPrice: 1250, Total: 2500}},
+	Subtotal:         2500,
+	Total:            2500,
+	Currency:         "EUR",
+}
+
+// smtpMail is one message received by fakeSMTPServer
+type smtpMail struct {
+	From string
+	To   []string
+	Data string
+}
+
+// fakeSMTPServer speaks just enough SMTP for net/smtp on a local port.
+// Recipients listed in reject are refused with a permanent error.
+type fakeSMTPServer struct {
+	listener net.Listener
+	reject   map[string]bool
+
+	mu   sync.Mutex
+	mail []smtpMail
+}
+
+// startFakeSMTPServer listens on a random local port until the test ends
+func startFakeSMTPServer(t *testing.T) *fakeSMTPServer {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &fakeSMTPServer{listener: listener, reject: make(map[string]bool)}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+// addr returns the host:port the server listens on
+func (s *fakeSMTPServer) addr() string {
+	return s.listener.Addr().String()
+}
+
+// received returns the messages accepted so far
+func (s *fakeSMTPServer) received() []smtpMail {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]smtpMail(nil), s.mail...)
+}
+
+// serve runs the SMTP conversation on one connection
+func (s *fakeSMTPServer) serve(conn net.Conn) {
+	defer conn.Close()
+	text := textproto.NewConn(conn)
+	var current smtpMail
+
+	text.PrintfLine("220 localhost fake ESMTP")
+	for {
+		line, err := text.ReadLine()
+		if err != nil {
+			return
+		}
+		verb := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+		switch verb {
+		case "EHLO", "HELO":
+			text.PrintfLine("250 localhost")
+		case "MAIL":
+			current = smtpMail{From: smtpAddress(line)}
+			text.PrintfLine("250 OK")
+		case "RCPT":
+			to := smtpAddress(line)
+			if s.reject[to] {
+				text.PrintfLine("550 no such user")
+				continue
+			}
+			current.To = append(current.To, to)
+			text.PrintfLine("250 OK")
+		case "DATA":
+			text.PrintfLine("354 end with <CRLF>.<CRLF>")
+			data, err := text.ReadDotBytes()
+			if err != nil {
+				return
+			}
+			current.Data = string(data)
+			s.mu.Lock()
+			s.mail = append(s.mail, current)
+			s.mu.Unlock()
+			text.PrintfLine("250 OK queued")
+		case "QUIT":
+			text.PrintfLine("221 bye")
+			return
+		default:
+			text.PrintfLine("502 not implemented")
+		}
+	}
+}
+
+// smtpAddress extracts the address from "MAIL FROM:<a@b>" or "RCPT TO:<a@b>"
+func smtpAddress(line string) string {
+	start, end := strings.Index(line, "<"), strings.Index(line, ">")
+	if start < 0 || end < start {
+		return ""
+	}
+	return line[start+1 : end]
+}
+
+func TestSMTPNotifierSendsConfirmation(t *testing.T) {
+	server := startFakeSMTPServer(t)
+	notifier, err := NewSMTPNotifier(server.addr(), "tickets@example.com", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := notifier.Send(ctx, TicketMessage(testEvent, testBooking)); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	received := server.received()
+	if len(received) != 1 {
+		t.Fatalf("server received %v messages, want 1", len(received))
+	}
+	got := received[0]
+	if got.From != "tickets@example.com" || len(got.To) != 1 || got.To[0] != "mary@example.com" {
+		t.Errorf("envelope from %q to %v, want tickets@example.com to [mary@example.com]", got.From, got.To)
+	}
+
+	msg, err := mail.ReadMessage(strings.NewReader(got.Data))
+	if err != nil {
+		t.Fatalf("parsing the received message: %v", err)
+	}
+	if subject := msg.Header.Get("Subject"); subject != "Your tickets for Go Conference" {
+		t.Errorf("Subject = %q", subject)
+	}
+	body, _ := ioutil.ReadAll(msg.Body)
+	for _, want := range []string{"Hello Mary,", "confirmation code ACDE-FGHJ", "2 x General at EUR 12.50 = EUR 25.00", "Total: EUR 25.00"} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("body does not contain %q:\n%s", want, body)
+		}
+	}
+}
+
+func TestSMTPNotifierReportsRejectedRecipient(t *testing.T) {
+	server := startFakeSMTPServer(t)
+	server.reject["mary@example.com"] = true
+	notifier, err := NewSMTPNotifier(server.addr(), "tickets@example.com", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = notifier.Send(context.Background(), TicketMessage(testEvent, testBooking))
+	var protoErr *textproto.Error
+	if !errors.As(err, &protoErr) || protoErr.Code != 550 {
+		t.Fatalf("Send = %v, want the server's 550 error", err)
+	}
+	if received := server.received(); len(received) != 0 {
+		t.Errorf("server received %v messages, want none", len(received))
+	}
+}
+
+func TestSMTPNotifierGivesUpWhenContextIsDone(t *testing.T) {
+	// A server that accepts connections but never greets
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	notifier, err := NewSMTPNotifier(listener.Addr().String(), "tickets@example.com", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if err := notifier.Send(ctx, TicketMessage(testEvent, testBooking)); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Send = %v, want context.DeadlineExceeded", err)
+	}
+}
+
+func TestMaildirNotifierRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	notifier, err := NewMaildirNotifier(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sent := TicketMessage(testEvent, testBooking)
+	if err := notifier.Send(context.Background(), sent); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	if files, _ := ioutil.ReadDir(filepath.Join(dir, "tmp")); len(files) != 0 {
+		t.Errorf("%v files left in tmp", len(files))
+	}
+	files, err := ioutil.ReadDir(filepath.Join(dir, "new"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 {
+		t.Fatalf("%v files in new, want 1", len(files))
+	}
+
+	data, err := ioutil.ReadFile(filepath.Join(dir, "new", files[0].Name()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg, err := mail.ReadMessage(strings.NewReader(string(data)))
+	if err != nil {
+		t.Fatalf("parsing the maildir message: %v", err)
+	}
+	if to := msg.Header.Get("To"); to != sent.To {
+		t.Errorf("To = %q, want %q", to, sent.To)
+	}
+	if subject := msg.Header.Get("Subject"); subject != sent.Subject {
+		t.Errorf("Subject = %q, want %q", subject, sent.Subject)
+	}
+	body, _ := ioutil.ReadAll(msg.Body)
+	if got := strings.ReplaceAll(string(body), "\r\n", "\n"); got != sent.Body {
+		t.Errorf("body = %q, want %q", got, sent.Body)
+	}
+}
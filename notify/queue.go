@@ -0,0 +1,179 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Result reports the outcome of delivering one message.
+// Err is the last error if the message could not be delivered after all attempts.
+type Result struct {
+	Message  Message
+	Worker   int
+	Attempts int
+	Err      error
+}
+
+// QueueConfig configures a Queue
+type QueueConfig struct {
+	// Workers is the number of messages delivered at the same time
+	Workers int
+	// Size is how many messages can wait before Enqueue blocks
+	Size int
+	// Retry decides how failed sends are retried
+	Retry RetryPolicy
+	// DeadLetters, if set, receives every message that failed all attempts
+	DeadLetters *DeadLetterFile
+}
+
+// ErrQueueClosed is returned by Enqueue once the queue is shutting down
+var ErrQueueClosed = errors.New("notify: delivery queue is shut down")
+
+// Queue delivers messages through a Notifier using a fixed number of workers,
+// so a burst of bookings never starts more concurrent sends than that.
+// Failed sends are retried with backoff before being moved to the dead letters.
+// Every delivery is reported on the Results channel, which must be drained.
+type Queue struct {
+	// pending counts messages that were enqueued but are not delivered yet.
+	// It is the first field so 64-bit atomic access is aligned on 32-bit platforms.
+	pending int64
+
+	notifier    Notifier
+	retry       RetryPolicy
+	deadLetters *DeadLetterFile
+
+	// ctx is passed to every send and cancelled when Shutdown runs out of time
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	jobs    chan Message
+	results chan Result
+	wg      sync.WaitGroup
+
+	// mu guards closed and undelivered
+	mu          sync.RWMutex
+	closed      bool
+	undelivered []Message
+}
+
+// NewQueue starts the configured number of workers sending messages through notifier
+func NewQueue(notifier Notifier, cfg QueueConfig) *Queue {
+	workers := cfg.Workers
+	if workers < 1 {
+		workers = 1
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	q := &Queue{
+		notifier:    notifier,
+		retry:       cfg.Retry,
+		deadLetters: cfg.DeadLetters,
+		ctx:         ctx,
+		cancel:      cancel,
+		jobs:        make(chan Message, cfg.Size),
+		results:     make(chan Result, cfg.Size),
+	}
+	for i := 1; i <= workers; i++ {
+		q.wg.Add(1)
+		go q.worker(i)
+	}
+
+	// Close the results channel once all workers are done,
+	// which ends any range loop over Results
+	go func() {
+		q.wg.Wait()
+		close(q.results)
+	}()
+	return q
+}
+
+// worker sends messages from the jobs channel until it is closed
+func (q *Queue) worker(id int) {
+	defer q.wg.Done()
+
+	for msg := range q.jobs {
+		attempts, err := sendWithRetry(q.ctx, q.notifier, msg, q.retry)
+		if err != nil {
+			if q.ctx.Err() != nil {
+				// Interrupted by shutdown rather than failed: remember it for the report
+				q.mu.Lock()
+				q.undelivered = append(q.undelivered, msg)
+				q.mu.Unlock()
+			}
+			q.addDeadLetter(msg, attempts, err)
+		}
+		atomic.AddInt64(&q.pending, -1)
+		q.results <- Result{Message: msg, Worker: id, Attempts: attempts, Err: err}
+	}
+}
+
+// addDeadLetter persists an undelivered message if dead letters are configured
+func (q *Queue) addDeadLetter(msg Message, attempts int, err error) {
+	if q.deadLetters == nil {
+		return
+	}
+	deadLetter := DeadLetter{Message: msg, Attempts: attempts, LastError: err.Error(), FailedAt: time.Now()}
+	if dlErr := q.deadLetters.Add(deadLetter); dlErr != nil {
+		log.Printf("notify: could not record dead letter for %v: %v", msg.To, dlErr)
+	}
+}
+
+// Enqueue adds a message to the queue, blocking while the queue is full.
+// It returns ErrQueueClosed once Shutdown has been called.
+func (q *Queue) Enqueue(msg Message) error {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+
+	if q.closed {
+		return ErrQueueClosed
+	}
+	atomic.AddInt64(&q.pending, 1)
+	q.jobs <- msg
+	return nil
+}
+
+// Depth returns the number of messages waiting or being delivered
+func (q *Queue) Depth() int {
+	return int(atomic.LoadInt64(&q.pending))
+}
+
+// Results returns the channel on which every delivery is reported
+func (q *Queue) Results() <-chan Result {
+	return q.results
+}
+
+// Shutdown stops accepting messages and lets the workers deliver the ones
+// already queued. If ctx is done first, in-flight sends are cancelled and
+// every message not yet delivered is moved to the dead letters instead.
+// It returns the messages that were left undelivered because of the deadline.
+func (q *Queue) Shutdown(ctx context.Context) []Message {
+	q.mu.Lock()
+	if !q.closed {
+		q.closed = true
+		close(q.jobs)
+	}
+	q.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		q.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		// Workers now fail the remaining messages immediately
+		q.cancel()
+		<-done
+	}
+	q.cancel()
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.undelivered
+}
@@ -0,0 +1,88 @@
+package notify
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"time"
+)
+
+// RetryPolicy decides how often and how long to wait before resending a failed message
+type RetryPolicy struct {
+	// MaxAttempts is the total number of sends, including the first one
+	MaxAttempts int
+	// BaseDelay is the backoff before the second attempt; it doubles for every further attempt
+	BaseDelay time.Duration
+	// MaxDelay caps the backoff between two attempts
+	MaxDelay time.Duration
+}
+
+// DefaultRetryPolicy tries a message five times over roughly half a minute
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 5,
+	BaseDelay:   time.Second,
+	MaxDelay:    30 * time.Second,
+}
+
+// Backoff returns how long to wait after the given failed attempt (starting at 1).
+// It uses "full jitter": a random duration up to the exponential delay, so
+// workers retrying at the same time do not hit the mail server in lockstep.
+func (p RetryPolicy) Backoff(attempt int) time.Duration {
+	delay := p.maxBackoff(attempt)
+	if delay <= 0 {
+		return 0
+	}
+	// Up to and including delay, unless that would overflow
+	n := int64(delay)
+	if n < math.MaxInt64 {
+		n++
+	}
+	return time.Duration(rand.Int63n(n))
+}
+
+// maxBackoff returns the exponential delay after the given failed attempt,
+// capped at MaxDelay if it is set
+func (p RetryPolicy) maxBackoff(attempt int) time.Duration {
+	delay := p.BaseDelay
+	for i := 1; i < attempt; i++ {
+		if p.MaxDelay > 0 && delay >= p.MaxDelay {
+			break
+		}
+		if delay > math.MaxInt64/2 {
+			delay = math.MaxInt64
+			break
+		}
+		delay *= 2
+	}
+	if p.MaxDelay > 0 && delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	return delay
+}
+
+// sendWithRetry sends msg until it succeeds, the policy gives up or ctx is done.
+// It returns the number of attempts made and the last error.
+func sendWithRetry(ctx context.Context, notifier Notifier, msg Message, policy RetryPolicy) (int, error) {
+	maxAttempts := policy.MaxAttempts
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+
+	var err error
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		if err = notifier.Send(ctx, msg); err == nil {
+			return attempt, nil
+		}
+		if ctx.Err() != nil {
+			return attempt, ctx.Err()
+		}
+		if attempt < maxAttempts {
+			select {
+			case <-time.After(policy.Backoff(attempt)):
+			case <-ctx.Done():
+				return attempt, ctx.Err()
+			}
+		}
+	}
+	return maxAttempts, err
+}
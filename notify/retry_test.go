@@ -0,0 +1,35 @@
+package notify
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMaxBackoff(t *testing.T) {
+	tests := []struct {
+		policy  RetryPolicy
+		attempt int
+		want    time.Duration
+	}{
+		{RetryPolicy{BaseDelay: time.Second}, 1, time.Second},
+		{RetryPolicy{BaseDelay: time.Second}, 4, 8 * time.Second},
+		{RetryPolicy{BaseDelay: time.Second, MaxDelay: 5 * time.Second}, 3, 4 * time.Second},
+		{RetryPolicy{BaseDelay: time.Second, MaxDelay: 5 * time.Second}, 4, 5 * time.Second},
+		{RetryPolicy{BaseDelay: time.Second}, 100, time.Duration(1<<63 - 1)},
+	}
+	for _, test := range tests {
+		if got := test.policy.maxBackoff(test.attempt); got != test.want {
+			t.Errorf("%+v.maxBackoff(%v) = %v, want %v", test.policy, test.attempt, got, test.want)
+		}
+	}
+}
+
+func TestBackoffStaysWithinMaxBackoff(t *testing.T) {
+	// Attempts past 54 saturate the delay at the largest duration
+	policy := RetryPolicy{BaseDelay: time.Millisecond, MaxAttempts: 100}
+	for attempt := 1; attempt <= 100; attempt++ {
+		if got, limit := policy.Backoff(attempt), policy.maxBackoff(attempt); got < 0 || got > limit {
+			t.Errorf("Backoff(%v) = %v, want between 0 and %v", attempt, got, limit)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/smtp"
+	"time"
+)
+
+// SMTPNotifier sends messages through an SMTP server
+type SMTPNotifier struct {
+	addr string
+	host string
+	from string
+	auth smtp.Auth
+}
+
+// NewSMTPNotifier creates a notifier for the server at addr (host:port).
+// PLAIN authentication is used when username is not empty.
+func NewSMTPNotifier(addr string, from string, username string, password string) (*SMTPNotifier, error) {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, fmt.Errorf("notify: invalid SMTP address %q: %w", addr, err)
+	}
+	if from == "" {
+		return nil, fmt.Errorf("notify: SMTP sender address is not set")
+	}
+
+	n := &SMTPNotifier{addr: addr, host: host, from: from}
+	if username != "" {
+		n.auth = smtp.PlainAuth("", username, password, host)
+	}
+	return n, nil
+}
+
+// Send delivers the message to the SMTP server. It works like smtp.SendMail
+// (including STARTTLS when the server offers it), but the connection is
+// closed as soon as ctx is done so a hanging server cannot block shutdown.
+func (n *SMTPNotifier) Send(ctx context.Context, msg Message) error {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", n.addr)
+	if err != nil {
+		return err
+	}
+
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-stop:
+		}
+	}()
+
+	client, err := smtp.NewClient(conn, n.host)
+	if err != nil {
+		conn.Close()
+		return contextError(ctx, err)
+	}
+	defer client.Close()
+
+	if err := n.deliver(client, msg); err != nil {
+		return contextError(ctx, err)
+	}
+	return nil
+}
+
+// deliver runs the SMTP conversation for one message
+func (n *SMTPNotifier) deliver(client *smtp.Client, msg Message) error {
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: n.host}); err != nil {
+			return err
+		}
+	}
+	if n.auth != nil {
+		if err := client.Auth(n.auth); err != nil {
+			return err
+		}
+	}
+	if err := client.Mail(n.from); err != nil {
+		return err
+	}
+	if err := client.Rcpt(msg.To); err != nil {
+		return err
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(formatMessage(n.from, msg)); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// contextError prefers the context's error, since a cancelled send
+// otherwise surfaces as a confusing "use of closed network connection"
+func contextError(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return err
+}
+
+// formatMessage renders msg as an RFC 5322 message with CRLF line endings
+func formatMessage(from string, msg Message) []byte {
+	var buf bytes.Buffer
+	if from != "" {
+		fmt.Fprintf(&buf, "From: %v\r\n", from)
+	}
+	fmt.Fprintf(&buf, "To: %v\r\n", msg.To)
+	fmt.Fprintf(&buf, "Subject: %v\r\n", msg.Subject)
+	fmt.Fprintf(&buf, "Date: %v\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&buf, "Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	buf.Write(bytes.ReplaceAll([]byte(msg.Body), []byte("\n"), []byte("\r\n")))
+	return buf.Bytes()
+}
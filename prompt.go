@@ -0,0 +1,102 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// errTooManyRetries is returned when a question was answered wrongly too often
+var errTooManyRetries = errors.New("too many invalid answers")
+
+// prompter asks the questions of the interactive prompt. It reads whole
+// lines, so answers may contain spaces, and asks again when an answer is
+// rejected. Reading from any io.Reader and writing to any io.Writer lets it
+// run over a terminal, a pipe or a script of answers alike.
+type prompter struct {
+	scanner *bufio.Scanner
+	out     io.Writer
+	// maxRetries is the number of rejected answers after which a question
+	// gives up with errTooManyRetries; 0 asks again forever
+	maxRetries int
+}
+
+// newPrompter creates a prompter reading answers from in and writing questions to out
+func newPrompter(in io.Reader, out io.Writer, maxRetries int) *prompter {
+	return &prompter{scanner: bufio.NewScanner(in), out: out, maxRetries: maxRetries}
+}
+
+// printf writes to the output of the prompter
+func (p *prompter) printf(format string, args ...interface{}) {
+	fmt.Fprintf(p.out, format, args...)
+}
+
+// readLine prints the question and returns the next line without surrounding
+// white space. It returns io.EOF once the input has ended.
+func (p *prompter) readLine(question string) (string, error) {
+	fmt.Fprintln(p.out, question)
+	if !p.scanner.Scan() {
+		if err := p.scanner.Err(); err != nil {
+			return "", err
+		}
+		return "", io.EOF
+	}
+	return strings.TrimSpace(p.scanner.Text()), nil
+}
+
+// ask repeats the question until check accepts the answer, printing the
+// reason for every rejected answer
+func (p *prompter) ask(question string, check func(answer string) error) (string, error) {
+	for attempt := 1; ; attempt++ {
+		answer, err := p.readLine(question)
+		if err != nil {
+			return "", err
+		}
+		err = check(answer)
+		if err == nil {
+			return answer, nil
+		}
+
+		p.printf("Error: %v\n", err)
+		if p.maxRetries > 0 && attempt >= p.maxRetries {
+			return "", errTooManyRetries
+		}
+	}
+}
+
+// askNumber asks for a whole number of at least min and, unless max is 0, at most max
+func (p *prompter) askNumber(question string, min uint, max uint) (uint, error) {
+	var number uint
+	_, err := p.ask(question, func(answer string) error {
+		n, err := strconv.ParseUint(answer, 10, 0)
+		if err != nil || n < uint64(min) || (max > 0 && n > uint64(max)) {
+			if max > 0 {
+				return fmt.Errorf("Please enter a number from %v to %v.", min, max)
+			}
+			return fmt.Errorf("Please enter a whole number of at least %v.", min)
+		}
+		number = uint(n)
+		return nil
+	})
+	return number, err
+}
+
+// askYesNo asks a question answered with y(es) or n(o)
+func (p *prompter) askYesNo(question string) (bool, error) {
+	var yes bool
+	_, err := p.ask(question, func(answer string) error {
+		switch strings.ToLower(answer) {
+		case "y", "yes":
+			yes = true
+		case "n", "no":
+			yes = false
+		default:
+			return errors.New("Please answer y or n.")
+		}
+		return nil
+	})
+	return yes, err
+}
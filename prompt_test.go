@@ -0,0 +1,107 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func TestGetUserInput(t *testing.T) {
+	input := "Mary Ann\n  van der Berg  \nmary@example.com\n2\n"
+	var out bytes.Buffer
+	p := newPrompter(strings.NewReader(input), &out, 3)
+
+	firstName, lastName, email, tickets, err := getUserInput(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if firstName != "Mary Ann" || lastName != "van der Berg" || email != "mary@example.com" || tickets != 2 {
+		t.Errorf("got %q %q %q %v, want Mary Ann, van der Berg, mary@example.com and 2 tickets",
+			firstName, lastName, email, tickets)
+	}
+	if strings.Contains(out.String(), "Error:") {
+		t.Errorf("valid answers were rejected:\n%v", out.String())
+	}
+}
+
+func TestGetUserInputAsksAgain(t *testing.T) {
+	input := "Mary Ann\nSmith\nnot an email\nmary@example.com\ntwo\n0\n3\n"
+	var out bytes.Buffer
+	p := newPrompter(strings.NewReader(input), &out, 3)
+
+	_, _, email, tickets, err := getUserInput(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if email != "mary@example.com" || tickets != 3 {
+		t.Errorf("got %q and %v tickets, want mary@example.com and 3", email, tickets)
+	}
+	if got := strings.Count(out.String(), "Please enter a whole number of at least 1."); got != 2 {
+		t.Errorf("ticket count rejected %v times, want 2:\n%v", got, out.String())
+	}
+	if got := strings.Count(out.String(), "Invalid email address"); got != 1 {
+		t.Errorf("email rejected %v times, want 1:\n%v", got, out.String())
+	}
+}
+
+func TestPromptTooManyRetries(t *testing.T) {
+	tests := []struct {
+		name       string
+		input      string
+		maxRetries int
+		want       error
+	}{
+		{"retries exhausted", "x\ny\nz\n5\n", 3, errTooManyRetries},
+		{"last retry accepted", "x\ny\n5\n", 3, nil},
+		{"no limit", "a\nb\nc\nd\ne\n5\n", 0, nil},
+	}
+	for _, test := range tests {
+		p := newPrompter(strings.NewReader(test.input), ioutil.Discard, test.maxRetries)
+		_, err := p.askNumber("How many? ", 1, 9)
+		if !errors.Is(err, test.want) {
+			t.Errorf("%v: err = %v, want %v", test.name, err, test.want)
+		}
+	}
+}
+
+func TestPromptEndOfInput(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{"no input", ""},
+		{"after the first name", "Mary Ann\n"},
+		{"after a rejected answer", "Mary Ann\nSmith\nmary@example.com\ntwo\n"},
+		// The unterminated line is still an answer; the next question hits the end
+		{"in the middle of a line", "Mary Ann\nSmi"},
+	}
+	for _, test := range tests {
+		p := newPrompter(strings.NewReader(test.input), ioutil.Discard, 3)
+		_, _, _, _, err := getUserInput(p)
+		if !errors.Is(err, io.EOF) {
+			t.Errorf("%v: err = %v, want io.EOF", test.name, err)
+		}
+	}
+}
+
+func TestAskYesNo(t *testing.T) {
+	tests := []struct {
+		input string
+		want  bool
+	}{
+		{"y\n", true},
+		{"YES\n", true},
+		{"n\n", false},
+		{"maybe\nNo\n", false},
+	}
+	for _, test := range tests {
+		p := newPrompter(strings.NewReader(test.input), ioutil.Discard, 3)
+		got, err := p.askYesNo("Continue? ")
+		if err != nil || got != test.want {
+			t.Errorf("answers %q: got %v, %v, want %v", test.input, got, err, test.want)
+		}
+	}
+}
@@ -0,0 +1,639 @@
+package main
+
+import (
+	"booking-app/booking"
+	"booking-app/notify"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// bookingRequest is the JSON body accepted by POST .../bookings.
+// Tickets are ordered either per tier in Items, or as NumberOfTickets of
+// one Tier (the first tier of the conference if Tier is empty).
+type bookingRequest struct {
+	FirstName       string                `json:"firstName"`
+	LastName        string                `json:"lastName"`
+	Email           string                `json:"email"`
+	NumberOfTickets uint                  `json:"numberOfTickets"`
+	Tier            string                `json:"tier"`
+	Items           []booking.TicketOrder `json:"items"`
+	PromoCode       string                `json:"promoCode"`
+}
+
+// orders returns the tickets asked for by the request
+func (req bookingRequest) orders(conference *booking.Conference) []booking.TicketOrder {
+	if len(req.Items) > 0 {
+		return req.Items
+	}
+	tier := req.Tier
+	if tier == "" {
+		tier = conference.Tiers()[0].ID
+	}
+	return []booking.TicketOrder{{Tier: tier, Quantity: req.NumberOfTickets}}
+}
+
+// holdRequest is the JSON body accepted by POST .../holds.
+// Tickets are ordered like in bookingRequest; TTLSeconds may shorten the hold.
+type holdRequest struct {
+	NumberOfTickets uint                  `json:"numberOfTickets"`
+	Tier            string                `json:"tier"`
+	Items           []booking.TicketOrder `json:"items"`
+	TTLSeconds      int                   `json:"ttlSeconds"`
+}
+
+// availability is the JSON body returned by GET .../availability
+type availability struct {
+	Conference string             `json:"conference"`
+	Tickets    uint               `json:"tickets"`
+	Remaining  uint               `json:"remaining"`
+	Currency   string             `json:"currency,omitempty"`
+	Tiers      []tierAvailability `json:"tiers"`
+}
+
+// tierAvailability reports the price and remaining tickets of one tier.
+// Held tickets are not remaining, but may go back on sale.
+type tierAvailability struct {
+	booking.Tier
+	Remaining uint `json:"remaining"`
+	Held      uint `json:"held"`
+}
+
+// conferenceInfo describes one conference in GET /conferences
+type conferenceInfo struct {
+	booking.Event
+	Remaining uint `json:"remaining"`
+}
+
+// apiError describes a single problem with a request
+type apiError struct {
+	Field   string `json:"field,omitempty"`
+	Message string `json:"message"`
+}
+
+// errorResponse is the JSON body returned for every failed request.
+// Validation failures use the same shape through booking.ValidationError.
+type errorResponse struct {
+	Errors []apiError `json:"errors"`
+}
+
+// server exposes the conferences of a registry over a JSON HTTP API
+type server struct {
+	registry   *booking.Registry
+	deliveries *notify.Queue
+	holdTTL    time.Duration
+}
+
+// newServer builds the HTTP handler with all API routes registered.
+// Every conference is served under /conferences/{id}/; the original
+// /bookings and /availability routes keep working for the default conference.
+func newServer(registry *booking.Registry, deliveries *notify.Queue, holdTTL time.Duration) http.Handler {
+	s := &server{registry: registry, deliveries: deliveries, holdTTL: holdTTL}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/conferences", s.handleConferences)
+	mux.HandleFunc("/conferences/", s.handleConference)
+	mux.HandleFunc("/bookings", s.handleDefaultConference)
+	mux.HandleFunc("/bookings/", s.handleDefaultConference)
+	mux.HandleFunc("/availability", s.handleDefaultConference)
+	return mux
+}
+
+// handleConferences serves GET /conferences
+func (s *server) handleConferences(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, apiError{Message: "method not allowed"})
+		return
+	}
+
+	conferences := []conferenceInfo{}
+	for _, conference := range s.registry.List() {
+		conferences = append(conferences, conferenceInfo{Event: conference.Event(), Remaining: conference.Remaining()})
+	}
+	writeJSON(w, http.StatusOK, conferences)
+}
+
+// handleConference routes /conferences/{id}/... to the conference with that ID
+func (s *server) handleConference(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/conferences/")
+	id, rest := path, ""
+	if i := strings.Index(path, "/"); i >= 0 {
+		id, rest = path[:i], path[i:]
+	}
+
+	conference, err := s.registry.Get(id)
+	if err != nil {
+		writeError(w, http.StatusNotFound, apiError{Message: "conference not found"})
+		return
+	}
+	s.route(w, r, conference, rest)
+}
+
+// handleDefaultConference serves the original routes for the default conference
+func (s *server) handleDefaultConference(w http.ResponseWriter, r *http.Request) {
+	conference, err := s.registry.Get(defaultEvent.ID)
+	if err != nil {
+		// The events file replaced the default conference; use the first one
+		conferences := s.registry.List()
+		if len(conferences) == 0 {
+			writeError(w, http.StatusNotFound, apiError{Message: "conference not found"})
+			return
+		}
+		conference = conferences[0]
+	}
+	s.route(w, r, conference, r.URL.Path)
+}
+
+// route dispatches a path below a conference to its handler
+func (s *server) route(w http.ResponseWriter, r *http.Request, conference *booking.Conference, path string) {
+	switch {
+	case path == "/bookings":
+		s.handleBookings(w, r, conference)
+	case strings.HasPrefix(path, "/bookings/"):
+		s.handleBooking(w, r, conference, strings.TrimPrefix(path, "/bookings/"))
+	case path == "/availability":
+		s.handleAvailability(w, r, conference)
+	case path == "/stats":
+		s.handleStats(w, r, conference)
+	case path == "/holds":
+		s.handleHolds(w, r, conference)
+	case strings.HasPrefix(path, "/holds/"):
+		s.handleHold(w, r, conference, strings.TrimPrefix(path, "/holds/"))
+	case path == "/cancellations":
+		s.handleCancellations(w, r, conference)
+	case path == "/waitlist":
+		s.handleWaitlist(w, r, conference)
+	case strings.HasPrefix(path, "/waitlist/"):
+		s.handleWaitlistEntry(w, r, conference, strings.TrimPrefix(path, "/waitlist/"))
+	default:
+		writeError(w, http.StatusNotFound, apiError{Message: "not found"})
+	}
+}
+
+// handleBookings serves GET and POST on .../bookings
+func (s *server) handleBookings(w http.ResponseWriter, r *http.Request, conference *booking.Conference) {
+	switch r.Method {
+	case http.MethodGet:
+		bookings, err := conference.Bookings()
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, apiError{Message: err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusOK, bookings)
+	case http.MethodPost:
+		s.createBooking(w, r, conference)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, apiError{Message: "method not allowed"})
+	}
+}
+
+// createBooking validates the request body and books the tickets
+func (s *server) createBooking(w http.ResponseWriter, r *http.Request, conference *booking.Conference) {
+	var req bookingRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, apiError{Message: "invalid JSON body"})
+		return
+	}
+
+	orders := req.orders(conference)
+	if rejectInvalid(w, validateUserInput(conference, req.FirstName, req.LastName, req.Email, orders, req.PromoCode)) {
+		return
+	}
+
+	userData, err := conference.BookOrder(booking.Order{
+		FirstName: req.FirstName,
+		LastName:  req.LastName,
+		Email:     req.Email,
+		Items:     orders,
+		PromoCode: req.PromoCode,
+	})
+	if errors.Is(err, booking.ErrNotEnoughTickets) {
+		// Another request booked the remaining tickets after validation
+		writeError(w, http.StatusConflict, apiError{Field: "numberOfTickets", Message: err.Error()})
+		return
+	}
+	if errors.Is(err, booking.ErrSalesClosed) {
+		writeError(w, http.StatusServiceUnavailable, apiError{Message: err.Error()})
+		return
+	}
+	if errors.Is(err, booking.ErrTicketLimit) {
+		// Another request by the same person booked after validation
+		writeError(w, http.StatusConflict, apiError{Field: "numberOfTickets", Message: err.Error()})
+		return
+	}
+	if errors.Is(err, booking.ErrPromoUsedUp) || errors.Is(err, booking.ErrPromoEmailLimit) {
+		// Another request redeemed the promo code after validation
+		writeError(w, http.StatusConflict, apiError{Field: "promoCode", Message: err.Error()})
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, apiError{Message: err.Error()})
+		return
+	}
+
+	if err := sendTicket(s.deliveries, conference, userData); err != nil {
+		// The booking stands; the ticket is only missing because the server is shutting down
+		log.Printf("Queueing ticket for %v: %v", userData.Email, err)
+	}
+
+	writeJSON(w, http.StatusCreated, userData)
+}
+
+// cancelRequest is the JSON body accepted by POST .../bookings/{id}/cancel.
+// Tickets are cancelled like bookingRequest orders them; with no tickets
+// given the whole booking is cancelled.
+type cancelRequest struct {
+	NumberOfTickets uint                  `json:"numberOfTickets"`
+	Tier            string                `json:"tier"`
+	Items           []booking.TicketOrder `json:"items"`
+	Reason          string                `json:"reason"`
+}
+
+// handleBooking serves GET and DELETE on .../bookings/{id} and POST on .../bookings/{id}/cancel.
+// Bookings are addressed by their numeric ID or by their confirmation code.
+func (s *server) handleBooking(w http.ResponseWriter, r *http.Request, conference *booking.Conference, path string) {
+	bookingID, action := path, ""
+	if i := strings.Index(path, "/"); i >= 0 {
+		bookingID, action = path[:i], path[i:]
+	}
+	if action != "" && action != "/cancel" {
+		writeError(w, http.StatusNotFound, apiError{Message: "booking not found"})
+		return
+	}
+	userData, err := findBooking(conference, bookingID)
+	if errors.Is(err, booking.ErrBookingNotFound) {
+		writeError(w, http.StatusNotFound, apiError{Message: "booking not found"})
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, apiError{Message: err.Error()})
+		return
+	}
+	id := userData.ID
+
+	switch {
+	case action == "" && r.Method == http.MethodGet:
+		writeJSON(w, http.StatusOK, userData)
+	case action == "" && r.Method == http.MethodDelete:
+		s.cancelBooking(w, conference, id, cancelRequest{})
+	case action == "/cancel" && r.Method == http.MethodPost:
+		var req cancelRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, apiError{Message: "invalid JSON body"})
+			return
+		}
+		s.cancelBooking(w, conference, id, req)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, apiError{Message: "method not allowed"})
+	}
+}
+
+// findBooking looks up a booking by its numeric ID or its confirmation code
+func findBooking(conference *booking.Conference, idOrCode string) (booking.UserData, error) {
+	if id, err := strconv.ParseUint(idOrCode, 10, 0); err == nil {
+		return conference.Get(uint(id))
+	}
+	return conference.GetByCode(idOrCode)
+}
+
+// cancelBooking cancels the requested tickets and sends the cancellation notice
+func (s *server) cancelBooking(w http.ResponseWriter, conference *booking.Conference, id uint, req cancelRequest) {
+	items := req.Items
+	if len(items) == 0 && req.NumberOfTickets > 0 {
+		tier := req.Tier
+		if tier == "" {
+			tier = conference.Tiers()[0].ID
+		}
+		items = []booking.TicketOrder{{Tier: tier, Quantity: req.NumberOfTickets}}
+	}
+
+	cancellation, err := conference.CancelTickets(id, items, req.Reason)
+	switch {
+	case errors.Is(err, booking.ErrBookingNotFound):
+		writeError(w, http.StatusNotFound, apiError{Message: "booking not found"})
+		return
+	case errors.Is(err, booking.ErrCancelTooMany), errors.Is(err, booking.ErrNoTickets):
+		writeError(w, http.StatusUnprocessableEntity, apiError{Field: "numberOfTickets", Message: err.Error()})
+		return
+	case errors.Is(err, booking.ErrUnknownTier), errors.Is(err, booking.ErrTierNotBooked):
+		writeError(w, http.StatusUnprocessableEntity, apiError{Field: "tier", Message: err.Error()})
+		return
+	case err != nil:
+		writeError(w, http.StatusInternalServerError, apiError{Message: err.Error()})
+		return
+	}
+
+	if err := sendCancellation(s.deliveries, conference, cancellation); err != nil {
+		log.Printf("Queueing cancellation notice for %v: %v", cancellation.Email, err)
+	}
+	writeJSON(w, http.StatusOK, cancellation)
+}
+
+// handleCancellations serves GET .../cancellations, the audit log of the conference
+func (s *server) handleCancellations(w http.ResponseWriter, r *http.Request, conference *booking.Conference) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, apiError{Message: "method not allowed"})
+		return
+	}
+
+	cancellations, err := conference.Cancellations()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, apiError{Message: err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, cancellations)
+}
+
+// handleWaitlist serves GET and POST on .../waitlist
+func (s *server) handleWaitlist(w http.ResponseWriter, r *http.Request, conference *booking.Conference) {
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, conference.Waitlist())
+	case http.MethodPost:
+		s.joinWaitlist(w, r, conference)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, apiError{Message: "method not allowed"})
+	}
+}
+
+// joinWaitlist puts the requested tickets on the waitlist of the conference
+func (s *server) joinWaitlist(w http.ResponseWriter, r *http.Request, conference *booking.Conference) {
+	var req bookingRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, apiError{Message: "invalid JSON body"})
+		return
+	}
+
+	orders := req.orders(conference)
+	if len(orders) != 1 {
+		writeError(w, http.StatusBadRequest, apiError{Field: "items", Message: "join the waitlist for one ticket type at a time"})
+		return
+	}
+	if rejectInvalid(w, validatePersonalDetails(conference, req.FirstName, req.LastName, req.Email, orders[0].Quantity)) {
+		return
+	}
+	entry, err := conference.JoinWaitlist(req.FirstName, req.LastName, req.Email, orders[0].Tier, orders[0].Quantity)
+	switch {
+	case errors.Is(err, booking.ErrNoTickets):
+		writeError(w, http.StatusUnprocessableEntity, apiError{Field: "numberOfTickets", Message: err.Error()})
+	case errors.Is(err, booking.ErrUnknownTier):
+		writeError(w, http.StatusUnprocessableEntity, apiError{Field: "tier", Message: err.Error()})
+	case errors.Is(err, booking.ErrWaitlistTooMany):
+		writeError(w, http.StatusUnprocessableEntity, apiError{Field: "numberOfTickets", Message: err.Error()})
+	case errors.Is(err, booking.ErrTicketsAvailable), errors.Is(err, booking.ErrTicketLimit):
+		writeError(w, http.StatusConflict, apiError{Field: "numberOfTickets", Message: err.Error()})
+	case errors.Is(err, booking.ErrSalesClosed):
+		writeError(w, http.StatusServiceUnavailable, apiError{Message: err.Error()})
+	case err != nil:
+		writeError(w, http.StatusInternalServerError, apiError{Message: err.Error()})
+	default:
+		writeJSON(w, http.StatusCreated, entry)
+	}
+}
+
+// handleWaitlistEntry serves DELETE on .../waitlist/{id} and POST on .../waitlist/{id}/accept
+func (s *server) handleWaitlistEntry(w http.ResponseWriter, r *http.Request, conference *booking.Conference, path string) {
+	entryID, action := path, ""
+	if i := strings.Index(path, "/"); i >= 0 {
+		entryID, action = path[:i], path[i:]
+	}
+	id, err := strconv.ParseUint(entryID, 10, 0)
+	if err != nil || (action != "" && action != "/accept") {
+		writeError(w, http.StatusNotFound, apiError{Message: "not found"})
+		return
+	}
+
+	switch {
+	case action == "" && r.Method == http.MethodDelete:
+		err = conference.LeaveWaitlist(uint(id))
+		if err == nil {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+	case action == "/accept" && r.Method == http.MethodPost:
+		var userData booking.UserData
+		userData, err = conference.AcceptOffer(uint(id))
+		if err == nil {
+			if err := sendTicket(s.deliveries, conference, userData); err != nil {
+				log.Printf("Queueing ticket for %v: %v", userData.Email, err)
+			}
+			writeJSON(w, http.StatusCreated, userData)
+			return
+		}
+	default:
+		writeError(w, http.StatusMethodNotAllowed, apiError{Message: "method not allowed"})
+		return
+	}
+
+	switch {
+	case errors.Is(err, booking.ErrWaitlistNotFound):
+		writeError(w, http.StatusNotFound, apiError{Message: "waitlist entry not found"})
+	case errors.Is(err, booking.ErrNoOffer):
+		writeError(w, http.StatusConflict, apiError{Message: err.Error()})
+	case errors.Is(err, booking.ErrTicketLimit):
+		writeError(w, http.StatusConflict, apiError{Field: "numberOfTickets", Message: err.Error()})
+	case errors.Is(err, booking.ErrSalesClosed):
+		writeError(w, http.StatusServiceUnavailable, apiError{Message: err.Error()})
+	default:
+		writeError(w, http.StatusInternalServerError, apiError{Message: err.Error()})
+	}
+}
+
+// handleHolds serves GET and POST on .../holds
+func (s *server) handleHolds(w http.ResponseWriter, r *http.Request, conference *booking.Conference) {
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, conference.Holds())
+	case http.MethodPost:
+		s.createHold(w, r, conference)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, apiError{Message: "method not allowed"})
+	}
+}
+
+// createHold reserves the requested tickets for at most the configured hold TTL
+func (s *server) createHold(w http.ResponseWriter, r *http.Request, conference *booking.Conference) {
+	var req holdRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, apiError{Message: "invalid JSON body"})
+		return
+	}
+
+	ttl := s.holdTTL
+	if requested := time.Duration(req.TTLSeconds) * time.Second; requested > 0 && requested < ttl {
+		ttl = requested
+	}
+	orders := bookingRequest{NumberOfTickets: req.NumberOfTickets, Tier: req.Tier, Items: req.Items}.orders(conference)
+
+	hold, err := conference.Reserve(orders, ttl)
+	switch {
+	case errors.Is(err, booking.ErrNoTickets):
+		writeError(w, http.StatusUnprocessableEntity, apiError{Field: "numberOfTickets", Message: err.Error()})
+	case errors.Is(err, booking.ErrUnknownTier):
+		writeError(w, http.StatusUnprocessableEntity, apiError{Field: "tier", Message: err.Error()})
+	case errors.Is(err, booking.ErrNotEnoughTickets):
+		writeError(w, http.StatusConflict, apiError{Field: "numberOfTickets", Message: err.Error()})
+	case errors.Is(err, booking.ErrSalesClosed):
+		writeError(w, http.StatusServiceUnavailable, apiError{Message: err.Error()})
+	case err != nil:
+		writeError(w, http.StatusInternalServerError, apiError{Message: err.Error()})
+	default:
+		writeJSON(w, http.StatusCreated, hold)
+	}
+}
+
+// handleHold serves DELETE on .../holds/{id} and POST on .../holds/{id}/confirm.
+// Confirming takes the attendee details of a bookingRequest; its tickets are ignored.
+func (s *server) handleHold(w http.ResponseWriter, r *http.Request, conference *booking.Conference, path string) {
+	holdID, action := path, ""
+	if i := strings.Index(path, "/"); i >= 0 {
+		holdID, action = path[:i], path[i:]
+	}
+	id, err := strconv.ParseUint(holdID, 10, 0)
+	if err != nil || (action != "" && action != "/confirm") {
+		writeError(w, http.StatusNotFound, apiError{Message: "not found"})
+		return
+	}
+
+	switch {
+	case action == "" && r.Method == http.MethodDelete:
+		err = conference.Release(uint(id))
+		if err == nil {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+	case action == "/confirm" && r.Method == http.MethodPost:
+		var req bookingRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, apiError{Message: "invalid JSON body"})
+			return
+		}
+		hold, holdErr := conference.Hold(uint(id))
+		if errors.Is(holdErr, booking.ErrHoldOffered) {
+			writeError(w, http.StatusForbidden, apiError{Message: holdErr.Error()})
+			return
+		}
+		if holdErr != nil {
+			writeError(w, http.StatusNotFound, apiError{Message: "hold not found"})
+			return
+		}
+		if rejectInvalid(w, validatePersonalDetails(conference, req.FirstName, req.LastName, req.Email, hold.NumberOfTickets)) {
+			return
+		}
+
+		var userData booking.UserData
+		userData, err = conference.Confirm(uint(id), booking.Order{
+			FirstName: req.FirstName,
+			LastName:  req.LastName,
+			Email:     req.Email,
+			PromoCode: req.PromoCode,
+		})
+		if err == nil {
+			if err := sendTicket(s.deliveries, conference, userData); err != nil {
+				log.Printf("Queueing ticket for %v: %v", userData.Email, err)
+			}
+			writeJSON(w, http.StatusCreated, userData)
+			return
+		}
+	default:
+		writeError(w, http.StatusMethodNotAllowed, apiError{Message: "method not allowed"})
+		return
+	}
+
+	switch {
+	case errors.Is(err, booking.ErrHoldNotFound):
+		writeError(w, http.StatusNotFound, apiError{Message: "hold not found"})
+	case errors.Is(err, booking.ErrHoldExpired):
+		writeError(w, http.StatusGone, apiError{Message: err.Error()})
+	case errors.Is(err, booking.ErrHoldOffered):
+		writeError(w, http.StatusForbidden, apiError{Message: err.Error()})
+	case errors.Is(err, booking.ErrTicketLimit):
+		writeError(w, http.StatusConflict, apiError{Field: "numberOfTickets", Message: err.Error()})
+	case errors.Is(err, booking.ErrSalesClosed):
+		writeError(w, http.StatusServiceUnavailable, apiError{Message: err.Error()})
+	case errors.Is(err, booking.ErrPromoUnknown), errors.Is(err, booking.ErrPromoExpired), errors.Is(err, booking.ErrPromoNotApplicable):
+		writeError(w, http.StatusUnprocessableEntity, apiError{Field: "promoCode", Message: err.Error()})
+	case errors.Is(err, booking.ErrPromoUsedUp), errors.Is(err, booking.ErrPromoEmailLimit):
+		writeError(w, http.StatusConflict, apiError{Field: "promoCode", Message: err.Error()})
+	default:
+		writeError(w, http.StatusInternalServerError, apiError{Message: err.Error()})
+	}
+}
+
+// handleAvailability serves GET .../availability
+func (s *server) handleAvailability(w http.ResponseWriter, r *http.Request, conference *booking.Conference) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, apiError{Message: "method not allowed"})
+		return
+	}
+
+	tiers := []tierAvailability{}
+	for _, tier := range conference.Tiers() {
+		tiers = append(tiers, tierAvailability{Tier: tier, Remaining: conference.RemainingInTier(tier.ID), Held: conference.HeldInTier(tier.ID)})
+	}
+
+	writeJSON(w, http.StatusOK, availability{
+		Conference: conference.Name(),
+		Tickets:    conference.Tickets(),
+		Remaining:  conference.Remaining(),
+		Currency:   conference.Event().Currency,
+		Tiers:      tiers,
+	})
+}
+
+// handleStats serves GET .../stats, the sales report of the conference.
+// ?top=n sets the number of email domains and largest orders.
+func (s *server) handleStats(w http.ResponseWriter, r *http.Request, conference *booking.Conference) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, apiError{Message: "method not allowed"})
+		return
+	}
+
+	var opts booking.StatsOptions
+	if top := r.URL.Query().Get("top"); top != "" {
+		n, err := strconv.Atoi(top)
+		if err != nil || n < 1 {
+			writeError(w, http.StatusBadRequest, apiError{Field: "top", Message: "top must be a positive number"})
+			return
+		}
+		opts.Top = n
+	}
+
+	stats, err := conference.Stats(opts)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, apiError{Message: err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, stats)
+}
+
+// rejectInvalid sends the response for a failed validation and reports whether it did.
+// Validation errors are sent field by field; any other error means validation could not run.
+func rejectInvalid(w http.ResponseWriter, err error) bool {
+	var validationErr *booking.ValidationError
+	switch {
+	case errors.As(err, &validationErr):
+		writeJSON(w, http.StatusUnprocessableEntity, validationErr)
+	case err != nil:
+		writeError(w, http.StatusInternalServerError, apiError{Message: err.Error()})
+	default:
+		return false
+	}
+	return true
+}
+
+// writeJSON encodes v as the JSON response body with the given status code
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeError sends one or more errors as a JSON error response
+func writeError(w http.ResponseWriter, status int, errs ...apiError) {
+	writeJSON(w, status, errorResponse{Errors: errs})
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"booking-app/booking"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+)
+
+// statsCommand reports the ticket sales of every conference, or of one, as
+// tables or as JSON for dashboards
+func statsCommand(env *environment, fs *flag.FlagSet, args []string) int {
+	conferenceID := fs.String("conference", "", "ID of the conference (default all)")
+	var opts booking.StatsOptions
+	fs.IntVar(&opts.Top, "top", booking.DefaultStatsTop, "number of email domains and largest orders to list")
+	asJSON := fs.Bool("json", false, "print the report as a JSON array with one object per conference")
+	if code, ok := parseArgs(fs, args, 0, 0); !ok {
+		return code
+	}
+	if code, ok := requireDataDir(env, fs.Name()); !ok {
+		return code
+	}
+
+	conferences := env.registry.List()
+	if *conferenceID != "" {
+		conference, err := env.registry.Get(*conferenceID)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 2
+		}
+		conferences = []*booking.Conference{conference}
+	}
+
+	report := make([]booking.Stats, 0, len(conferences))
+	for _, conference := range conferences {
+		stats, err := conference.Stats(opts)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		report = append(report, stats)
+	}
+
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(report); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		return 0
+	}
+	for i, stats := range report {
+		if i > 0 {
+			fmt.Println()
+		}
+		printStats(os.Stdout, stats)
+	}
+	return 0
+}
+
+// printStats prints the report of one conference as a summary and tables
+func printStats(out io.Writer, stats booking.Stats) {
+	tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	defer tw.Flush()
+
+	fmt.Fprintf(tw, "%v\n", stats.Name)
+	fmt.Fprintf(tw, "  Tickets sold\t%v of %v (%.1f%%)\n", stats.TicketsSold, stats.Tickets, stats.PercentSold)
+	fmt.Fprintf(tw, "  Tickets held\t%v\n", stats.TicketsHeld)
+	fmt.Fprintf(tw, "  Tickets remaining\t%v\n", stats.TicketsRemaining)
+	fmt.Fprintf(tw, "  Bookings\t%v\n", stats.Bookings)
+	fmt.Fprintf(tw, "  Tickets per booking\t%.2f\n", stats.AverageTickets)
+	fmt.Fprintf(tw, "  Revenue\t%v\n", booking.FormatAmount(stats.Revenue, stats.Currency))
+	if stats.UndatedBookings > 0 {
+		fmt.Fprintf(tw, "  Bookings without a date\t%v\n", stats.UndatedBookings)
+	}
+	if stats.Bookings == 0 {
+		return
+	}
+
+	fmt.Fprintf(tw, "\nTop email domains\n  DOMAIN\tBOOKINGS\tTICKETS\n")
+	for _, domain := range stats.TopEmailDomains {
+		fmt.Fprintf(tw, "  %v\t%v\t%v\n", domain.Domain, domain.Bookings, domain.Tickets)
+	}
+
+	fmt.Fprintf(tw, "\nBookings per day\n  DAY\tBOOKINGS\tTICKETS\n")
+	for _, day := range stats.BookingsPerDay {
+		fmt.Fprintf(tw, "  %v\t%v\t%v\n", day.Start.Format("2006-01-02"), day.Bookings, day.Tickets)
+	}
+
+	fmt.Fprintf(tw, "\nBookings per hour\n  HOUR\tBOOKINGS\tTICKETS\n")
+	for _, hour := range stats.BookingsPerHour {
+		fmt.Fprintf(tw, "  %v\t%v\t%v\n", hour.Start.Format("2006-01-02 15:00"), hour.Bookings, hour.Tickets)
+	}
+
+	fmt.Fprintf(tw, "\nLargest orders\n  ID\tNAME\tEMAIL\tTICKETS\tTOTAL\n")
+	for _, order := range stats.LargestOrders {
+		fmt.Fprintf(tw, "  %v\t%v %v\t%v\t%v\t%v\n", order.ID, order.FirstName, order.LastName, order.Email,
+			order.Tickets, booking.FormatAmount(order.Total, stats.Currency))
+	}
+}